@@ -2,11 +2,80 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"time"
 )
 
-type cacheHandler interface {
+var ErrDeleteNotSupported = errors.New("cache: handler does not support delete")
+
+// Backend 缓存后端，实现需支持并发调用
+type Backend interface {
+	// Get 读取 key，不存在时返回 ok=false；返回的切片调用方不可修改
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set 写入 key，ttl <= 0 表示不过期
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete 删除 key，不存在的 key 忽略
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// LegacyHandler 旧版 Get + Scan 形式的缓存实例，Scan 解码的是上一次 Get 的结果
+type LegacyHandler interface {
 	Get(ctx context.Context, key string) (interface{}, error)
 	Set(ctx context.Context, key interface{}, value interface{}, duration time.Duration) error
 	Scan(pointer interface{}, mapping ...map[string]string) error
 }
+
+type legacyDeleter interface {
+	Remove(ctx context.Context, keys ...interface{}) (value interface{}, err error)
+}
+
+// NewHandlerBackend 将 LegacyHandler 适配为 Backend
+// 值以字符串形式写入；Get 与 Scan 在同一把锁内完成，保证并发安全；
+// handler 实现了 Remove(ctx, keys...) 时支持 Delete，否则返回 ErrDeleteNotSupported
+func NewHandlerBackend(h LegacyHandler) Backend {
+	return &handlerBackend{h: h}
+}
+
+type handlerBackend struct {
+	mu sync.Mutex
+	h  LegacyHandler
+}
+
+func (b *handlerBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	val, err := b.h.Get(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	if val == nil {
+		return nil, false, nil
+	}
+	var s string
+	if err = b.h.Scan(&s); err != nil {
+		return nil, false, err
+	}
+	return []byte(s), true, nil
+}
+
+func (b *handlerBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return b.h.Set(ctx, key, string(value), ttl)
+}
+
+func (b *handlerBackend) Delete(ctx context.Context, keys ...string) error {
+	d, ok := b.h.(legacyDeleter)
+	if !ok {
+		return ErrDeleteNotSupported
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	args := make([]interface{}, len(keys))
+	for i, k := range keys {
+		args[i] = k
+	}
+	_, err := d.Remove(ctx, args...)
+	return err
+}
@@ -0,0 +1,222 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/golang/snappy"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec 缓存值的序列化方式
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	JSONCodec    Codec = jsonCodec{}
+	GobCodec     Codec = gobCodec{}
+	MsgpackCodec Codec = msgpackCodec{}
+)
+
+// Compression 压缩算法
+type Compression uint8
+
+const (
+	CompressNone Compression = iota
+	CompressGzip
+	CompressSnappy
+)
+
+// frameMagic 带头部的缓存值首字节；JSON 不会以该字节开头，因此可与未开启编码选项时写入的纯 JSON 区分
+const frameMagic byte = 0xCA
+
+// 头部：magic、版本号、codec、压缩算法
+const frameHeaderLen = 4
+
+// frameFlagMeta 头部 codec 字节的最高位，标记值为带刷新元数据的 cacheEntry
+const frameFlagMeta uint8 = 0x80
+
+const (
+	codecCustom uint8 = iota
+	codecJSON
+	codecGob
+	codecMsgpack
+)
+
+// errVersionMismatch 缓存值版本与当前不一致，按未命中处理
+var errVersionMismatch = errors.New("cache: version mismatch")
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// gobCodec gob 无法编码 nil 指针，nil 指针编码为空字节，解码时保持零值
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	if rv := reflect.ValueOf(v); v == nil || (rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		return []byte{}, nil
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error)      { return msgpack.Marshal(v) }
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error { return msgpack.Unmarshal(data, v) }
+
+func codecID(c Codec) uint8 {
+	switch c.(type) {
+	case jsonCodec:
+		return codecJSON
+	case gobCodec:
+		return codecGob
+	case msgpackCodec:
+		return codecMsgpack
+	default:
+		return codecCustom
+	}
+}
+
+func builtinCodec(id uint8) Codec {
+	switch id {
+	case codecJSON:
+		return JSONCodec
+	case codecGob:
+		return GobCodec
+	case codecMsgpack:
+		return MsgpackCodec
+	default:
+		return nil
+	}
+}
+
+// serializer 按加载选项编解码缓存值
+// 未设置任何编码选项时为纯 JSON，与旧版本写入的数据兼容；否则写入带头部的格式
+type serializer struct {
+	framed      bool
+	codec       Codec
+	compression Compression
+	minCompress int
+	version     uint8
+	meta        bool // 值为 cacheEntry，与普通值互不识别
+}
+
+func (s *serializer) marshal(v interface{}) ([]byte, error) {
+	if !s.framed {
+		return json.Marshal(v)
+	}
+
+	codec := s.codec
+	if codec == nil {
+		codec = JSONCodec
+	}
+	payload, err := codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	compression := CompressNone
+	if s.compression != CompressNone && len(payload) >= s.minCompress {
+		if payload, err = compress(s.compression, payload); err != nil {
+			return nil, err
+		}
+		compression = s.compression
+	}
+
+	out := make([]byte, frameHeaderLen, frameHeaderLen+len(payload))
+	id := codecID(codec)
+	if s.meta {
+		id |= frameFlagMeta
+	}
+	out[0], out[1], out[2], out[3] = frameMagic, s.version, id, byte(compression)
+	return append(out, payload...), nil
+}
+
+// unmarshal 解码缓存值；版本不一致或是否带元数据与当前不一致时返回 errVersionMismatch
+// 头部中的 codec 为内置 codec 时按头部解码，便于切换 codec 时新旧实例共存
+func (s *serializer) unmarshal(data []byte, v interface{}) error {
+	if len(data) == 0 || data[0] != frameMagic {
+		if s.framed && s.version != 0 {
+			return errVersionMismatch
+		}
+		return json.Unmarshal(data, v)
+	}
+	if len(data) < frameHeaderLen {
+		return errors.New("cache: truncated frame header")
+	}
+	if data[1] != s.version || (data[2]&frameFlagMeta != 0) != s.meta {
+		return errVersionMismatch
+	}
+
+	codec := builtinCodec(data[2] &^ frameFlagMeta)
+	if codec == nil {
+		codec = s.codec
+	}
+	if codec == nil {
+		return fmt.Errorf("cache: unknown codec %d", data[2])
+	}
+
+	payload, err := decompress(Compression(data[3]), data[frameHeaderLen:])
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(payload, v)
+}
+
+func compress(c Compression, data []byte) ([]byte, error) {
+	switch c {
+	case CompressGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CompressSnappy:
+		return snappy.Encode(nil, data), nil
+	default:
+		return nil, fmt.Errorf("cache: unknown compression %d", c)
+	}
+}
+
+func decompress(c Compression, data []byte) ([]byte, error) {
+	switch c {
+	case CompressNone:
+		return data, nil
+	case CompressGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case CompressSnappy:
+		return snappy.Decode(nil, data)
+	default:
+		return nil, fmt.Errorf("cache: unknown compression %d", c)
+	}
+}
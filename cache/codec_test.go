@@ -0,0 +1,114 @@
+package cache_test
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/supernarsi/gotool/cache"
+)
+
+func TestGetIdMapCodec(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []cache.Option
+	}{
+		{"json", []cache.Option{cache.WithCodec(cache.JSONCodec)}},
+		{"gob", []cache.Option{cache.WithCodec(cache.GobCodec)}},
+		{"msgpack", []cache.Option{cache.WithCodec(cache.MsgpackCodec)}},
+		{"gzip", []cache.Option{cache.WithCompression(cache.CompressGzip, 0)}},
+		{"msgpack snappy", []cache.Option{cache.WithCodec(cache.MsgpackCodec), cache.WithCompression(cache.CompressSnappy, 0)}},
+		{"gob with meta", []cache.Option{cache.WithCodec(cache.GobCodec), cache.WithStaleWhileRevalidate(time.Minute)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			h := cache.NewLRU(0, 0)
+			for i := 0; i < 2; i++ {
+				got, err := cache.LoadIdMap(context.Background(), h, "product:codec", productFetcher(&calls, 0), productId, time.Minute, tt.opts...)
+				if err != nil || len(got) != 2 || got[1].Name != "apple" || got[2].Name != "banana" {
+					t.Errorf("got result %v, err %v", got, err)
+				}
+			}
+			if calls != 1 {
+				t.Errorf("fetcher called %d times, want 1", calls)
+			}
+		})
+	}
+}
+
+func TestGetIdMapCompressionThreshold(t *testing.T) {
+	ctx := context.Background()
+	fetcher := func(ctx context.Context) ([]*product, error) {
+		return []*product{{Id: 1, Name: strings.Repeat("a", 1000)}}, nil
+	}
+	tests := []struct {
+		name    string
+		minSize int
+		want    byte
+	}{
+		{"below threshold", 4096, byte(cache.CompressNone)},
+		{"above threshold", 512, byte(cache.CompressGzip)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := cache.NewLRU(0, 0)
+			cache.GetIdMap(ctx, h, "product:gzip", fetcher, productId, time.Minute, cache.WithCompression(cache.CompressGzip, tt.minSize))
+			raw, _, _ := h.Get(ctx, "product:gzip")
+			if len(raw) < 4 || raw[3] != tt.want {
+				t.Errorf("got header %v, want compression %d", raw[:4], tt.want)
+			}
+			if got := cache.GetIdMap(ctx, h, "product:gzip", fetcher, productId, time.Minute, cache.WithCompression(cache.CompressGzip, tt.minSize)); len(got[1].Name) != 1000 {
+				t.Errorf("got name length %d, want 1000", len(got[1].Name))
+			}
+		})
+	}
+}
+
+func TestGetIdMapVersion(t *testing.T) {
+	var calls int32
+	ctx := context.Background()
+	h := cache.NewLRU(0, 0)
+	load := func(opts ...cache.Option) {
+		if got, err := cache.LoadIdMap(ctx, h, "product:version", productFetcher(&calls, 0), productId, time.Minute, opts...); err != nil || len(got) != 2 {
+			t.Errorf("got result %v, err %v", got, err)
+		}
+	}
+
+	// 纯 JSON 数据可被版本 0 的读取方直接使用
+	load()
+	load(cache.WithCodec(cache.MsgpackCodec))
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("fetcher called %d times, want 1", got)
+	}
+
+	// 版本变更视为未命中，重新回源并覆盖
+	load(cache.WithVersion(2))
+	load(cache.WithVersion(2))
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("fetcher called %d times, want 2", got)
+	}
+
+	// 切换 codec 后仍可读取头部标记的内置 codec 数据
+	load(cache.WithVersion(2), cache.WithCodec(cache.GobCodec))
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("fetcher called %d times, want 2", got)
+	}
+}
+
+func TestGetByIdsGobNegative(t *testing.T) {
+	h := cache.NewLRU(0, 0)
+	r := &batchRecorder{db: map[uint]string{1: "apple"}}
+	opts := []cache.Option{cache.WithCodec(cache.GobCodec), cache.WithEmptyTTL(time.Minute)}
+	for i := 0; i < 2; i++ {
+		got, err := cache.LoadByIds(context.Background(), h, "product:", []uint{1, 9}, r.fetch, productId, time.Minute, opts...)
+		if err != nil || len(got) != 1 || got[1].Name != "apple" {
+			t.Errorf("got result %v, err %v", got, err)
+		}
+	}
+	if len(r.calls) != 1 {
+		t.Errorf("got fetch calls %v, want 1 call", r.calls)
+	}
+}
@@ -2,29 +2,31 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
-type cCtx context.Context
+type cCtx = context.Context
 type FetcherFunc[T any] func(ctx cCtx) ([]*T, error)
 type GetIdFunc[T any] func(item *T) uint
 type GetKeyFunc[T any] func(item *T) string
 
-func GetIdMap[T any](ctx cCtx, cacheIns cacheHandler, cKey string, fetcher FetcherFunc[T], getId GetIdFunc[T], duration time.Duration) map[uint]*T {
-	var resultMap map[uint]T
+// GetIdMap 读取以 id 为 key 的缓存 map，未命中时回源并写入缓存
+// 同一 cKey 的并发未命中只会触发一次 fetcher，其余调用方等待并共享结果；回源失败时返回 nil
+func GetIdMap[T any](ctx cCtx, cacheIns Backend, cKey string, fetcher FetcherFunc[T], getId GetIdFunc[T], duration time.Duration, opts ...Option) map[uint]*T {
+	ptrMap, err := LoadIdMap(ctx, cacheIns, cKey, fetcher, getId, duration, opts...)
+	if err != nil && !errors.Is(err, ErrBackend) {
+		return nil
+	}
+	return ptrMap
+}
 
-	if !getFromCache(ctx, cacheIns, cKey, &resultMap) {
-		items, err := fetcher(ctx)
-		if err != nil {
-			return nil
-		}
-		resultMap = make(map[uint]T, len(items))
-		for _, item := range items {
-			resultMap[getId(item)] = *item
-		}
-		if len(resultMap) > 0 {
-			setItemIdMapToCache(ctx, cacheIns, cKey, resultMap, duration)
-		}
+// LoadIdMap 同 GetIdMap，但返回错误
+// 回源失败时返回 nil 和回源错误；仅缓存读写失败时返回回源得到的 map 和包装了 ErrBackend 的错误
+func LoadIdMap[T any](ctx cCtx, cacheIns Backend, cKey string, fetcher FetcherFunc[T], getId GetIdFunc[T], duration time.Duration, opts ...Option) (map[uint]*T, error) {
+	resultMap, err := loadMap(ctx, cacheIns, cKey, fetcher, getId, duration, opts)
+	if resultMap == nil {
+		return nil, err
 	}
 
 	ptrMap := make(map[uint]*T, len(resultMap))
@@ -32,25 +34,24 @@ func GetIdMap[T any](ctx cCtx, cacheIns cacheHandler, cKey string, fetcher Fetch
 		newItem := item
 		ptrMap[id] = &newItem
 	}
-	return ptrMap
+	return ptrMap, err
 }
 
-func GetKeyMap[T any](ctx cCtx, cacheIns cacheHandler, key string, fetcher FetcherFunc[T], getKey GetKeyFunc[T], duration time.Duration) map[string]*T {
-	var resultMap map[string]T
-
-	if !getFromCache(ctx, cacheIns, key, &resultMap) {
-		items, err := fetcher(ctx)
-		if err != nil {
-			return nil
-		}
+// GetKeyMap 读取以字符串为 key 的缓存 map，未命中时回源并写入缓存
+// 同一 key 的并发未命中只会触发一次 fetcher，其余调用方等待并共享结果；回源失败时返回 nil
+func GetKeyMap[T any](ctx cCtx, cacheIns Backend, key string, fetcher FetcherFunc[T], getKey GetKeyFunc[T], duration time.Duration, opts ...Option) map[string]*T {
+	ptrMap, err := LoadKeyMap(ctx, cacheIns, key, fetcher, getKey, duration, opts...)
+	if err != nil && !errors.Is(err, ErrBackend) {
+		return nil
+	}
+	return ptrMap
+}
 
-		resultMap = make(map[string]T, len(items))
-		for _, item := range items {
-			resultMap[getKey(item)] = *item
-		}
-		if len(resultMap) > 0 {
-			setItemKeyMapToCache(ctx, cacheIns, key, resultMap, duration)
-		}
+// LoadKeyMap 同 GetKeyMap，但返回错误，错误语义与 LoadIdMap 一致
+func LoadKeyMap[T any](ctx cCtx, cacheIns Backend, key string, fetcher FetcherFunc[T], getKey GetKeyFunc[T], duration time.Duration, opts ...Option) (map[string]*T, error) {
+	resultMap, err := loadMap(ctx, cacheIns, key, fetcher, getKey, duration, opts)
+	if resultMap == nil {
+		return nil, err
 	}
 
 	ptrMap := make(map[string]*T, len(resultMap))
@@ -58,39 +59,29 @@ func GetKeyMap[T any](ctx cCtx, cacheIns cacheHandler, key string, fetcher Fetch
 		newItem := item
 		ptrMap[itemKey] = &newItem
 	}
-	return ptrMap
+	return ptrMap, err
 }
 
-func getFromCache[T any](ctx cCtx, cacheIns cacheHandler, key string, dest *T) bool {
-	cacheVal, err := cacheIns.Get(ctx, key)
-	if err != nil {
-		return false
-	}
-	if cacheVal == nil {
-		return false
-	}
-	if err = cacheIns.Scan(dest); err != nil {
-		return false
-	}
-	return true
-}
-
-func setItemIdMapToCache[T any](ctx cCtx, cacheIns cacheHandler, key string, value map[uint]T, duration time.Duration) bool {
-	if len(value) == 0 {
-		return true
-	}
-	if err := cacheIns.Set(ctx, key, value, duration); err != nil {
-		return false
-	}
-	return true
-}
-
-func setItemKeyMapToCache[T any](ctx cCtx, cacheIns cacheHandler, key string, value map[string]T, duration time.Duration) bool {
-	if len(value) == 0 {
-		return true
-	}
-	if err := cacheIns.Set(ctx, key, value, duration); err != nil {
-		return false
+// loadMap 将 fetcher 的结果按 keyOf 转为 map 后走统一的加载流程
+// 返回的 map 可能被多个调用方共享，调用方不可修改
+func loadMap[K comparable, T any](ctx cCtx, cacheIns Backend, key string, fetcher FetcherFunc[T], keyOf func(item *T) K, duration time.Duration, opts []Option) (map[K]T, error) {
+	l := &loader[map[K]T]{
+		cacheIns: cacheIns,
+		key:      key,
+		fetch: func(ctx cCtx) (map[K]T, error) {
+			items, err := fetcher(ctx)
+			if err != nil {
+				return nil, err
+			}
+			resultMap := make(map[K]T, len(items))
+			for _, item := range items {
+				resultMap[keyOf(item)] = *item
+			}
+			return resultMap, nil
+		},
+		empty:    func(v map[K]T) bool { return len(v) == 0 },
+		duration: duration,
+		opts:     newLoadOptions(opts),
 	}
-	return true
+	return l.load(ctx)
 }
@@ -0,0 +1,452 @@
+package cache_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/supernarsi/gotool/cache"
+)
+
+type product struct {
+	Id   uint
+	Name string
+}
+
+// memHandler 模拟 Get + Scan 形式的缓存实例
+type memHandler struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	last []byte
+}
+
+func newMemHandler() *memHandler {
+	return &memHandler{data: make(map[string][]byte)}
+}
+
+func (m *memHandler) Get(ctx context.Context, key string) (interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	val, ok := m.data[key]
+	if !ok {
+		return nil, nil
+	}
+	m.last = val
+	return val, nil
+}
+
+func (m *memHandler) Set(ctx context.Context, key interface{}, value interface{}, duration time.Duration) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key.(string)] = b
+	return nil
+}
+
+func (m *memHandler) Scan(pointer interface{}, mapping ...map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return json.Unmarshal(m.last, pointer)
+}
+
+func productFetcher(calls *int32, delay time.Duration) cache.FetcherFunc[product] {
+	return func(ctx context.Context) ([]*product, error) {
+		atomic.AddInt32(calls, 1)
+		time.Sleep(delay)
+		return []*product{{Id: 1, Name: "apple"}, {Id: 2, Name: "banana"}}, nil
+	}
+}
+
+func productId(item *product) uint { return item.Id }
+
+func productName(item *product) string { return item.Name }
+
+func TestGetIdMap(t *testing.T) {
+	var calls int32
+	h := cache.NewLRU(0, 0)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		got := cache.GetIdMap(ctx, h, "product:id", productFetcher(&calls, 0), productId, time.Minute)
+		if len(got) != 2 || got[1].Name != "apple" || got[2].Name != "banana" {
+			t.Errorf("got result %v", got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("fetcher called %d times, want 1", calls)
+	}
+}
+
+func TestGetKeyMap(t *testing.T) {
+	var calls int32
+	h := cache.NewLRU(0, 0)
+	got := cache.GetKeyMap(context.Background(), h, "product:name", productFetcher(&calls, 0), productName, time.Minute)
+	if len(got) != 2 || got["apple"].Id != 1 || got["banana"].Id != 2 {
+		t.Errorf("got result %v", got)
+	}
+}
+
+func TestGetIdMapLegacyHandler(t *testing.T) {
+	var calls int32
+	h := newMemHandler()
+	b := cache.NewHandlerBackend(h)
+	for i := 0; i < 3; i++ {
+		if got := cache.GetIdMap(context.Background(), b, "product:legacy", productFetcher(&calls, 0), productId, time.Minute); len(got) != 2 || got[2].Name != "banana" {
+			t.Errorf("got result %v", got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("fetcher called %d times, want 1", calls)
+	}
+	if err := b.Delete(context.Background(), "product:legacy"); !errors.Is(err, cache.ErrDeleteNotSupported) {
+		t.Errorf("got err %v, want %v", err, cache.ErrDeleteNotSupported)
+	}
+}
+
+func TestGetIdMapFetchError(t *testing.T) {
+	fetcher := func(ctx context.Context) ([]*product, error) {
+		return nil, errors.New("db down")
+	}
+	if got := cache.GetIdMap(context.Background(), cache.NewLRU(0, 0), "product:err", fetcher, productId, time.Minute); got != nil {
+		t.Errorf("got result %v, want nil", got)
+	}
+}
+
+func TestGetIdMapSingleflight(t *testing.T) {
+	var calls int32
+	h := cache.NewLRU(0, 0)
+	fetcher := productFetcher(&calls, 50*time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := cache.GetIdMap(context.Background(), h, "product:sf", fetcher, productId, time.Minute); len(got) != 2 {
+				t.Errorf("got result %v", got)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("fetcher called %d times, want 1", calls)
+	}
+}
+
+func TestGetIdMapSingleflightScope(t *testing.T) {
+	var calls int32
+	fetcher := productFetcher(&calls, 50*time.Millisecond)
+	a, b := cache.NewLRU(0, 0), cache.NewLRU(0, 0)
+	ctx := context.Background()
+
+	// 不同后端与不同值类型即使 key 相同也各自回源并写入各自的后端
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		if got := cache.GetIdMap(ctx, a, "product:scope", fetcher, productId, time.Minute); len(got) != 2 {
+			t.Errorf("got result %v", got)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if got := cache.GetIdMap(ctx, b, "product:scope", fetcher, productId, time.Minute); len(got) != 2 {
+			t.Errorf("got result %v", got)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if got, err := cache.LoadKeyMap(ctx, a, "product:scope", fetcher, productName, time.Minute); err != nil || len(got) != 2 {
+			t.Errorf("got result %v %v", got, err)
+		}
+	}()
+	wg.Wait()
+
+	if calls != 3 {
+		t.Errorf("fetcher called %d times, want 3", calls)
+	}
+	for _, h := range []cache.Backend{a, b} {
+		if _, ok, _ := h.Get(ctx, "product:scope"); !ok {
+			t.Error("backend was not written")
+		}
+	}
+}
+
+func TestGetIdMapWaiterCancel(t *testing.T) {
+	var calls int32
+	h := cache.NewLRU(0, 0)
+	fetcher := productFetcher(&calls, 200*time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if got := cache.GetIdMap(context.Background(), h, "product:cancel", fetcher, productId, time.Minute); len(got) != 2 {
+			t.Errorf("got result %v", got)
+		}
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if got := cache.GetIdMap(ctx, h, "product:cancel", fetcher, productId, time.Minute); got != nil {
+		t.Errorf("got result %v, want nil", got)
+	}
+	if time.Since(start) > 150*time.Millisecond {
+		t.Errorf("waiter did not honor ctx cancellation")
+	}
+
+	<-done
+	if calls != 1 {
+		t.Errorf("fetcher called %d times, want 1", calls)
+	}
+}
+
+func TestLoadIdMapTimeout(t *testing.T) {
+	h := cache.NewLRU(0, 0)
+	release := make(chan struct{})
+	defer close(release)
+	hang := func(ctx context.Context) ([]*product, error) {
+		<-release // 不响应 ctx 的回源
+		return nil, nil
+	}
+
+	start := time.Now()
+	_, err := cache.LoadIdMap(context.Background(), h, "product:hang", hang, productId, time.Minute, cache.WithLoadTimeout(30*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second {
+		t.Errorf("got err %v after %v, want %v", err, time.Since(start), context.DeadlineExceeded)
+	}
+
+	// 超时后释放该 key，后续未命中重新回源
+	var calls int32
+	if got := cache.GetIdMap(context.Background(), h, "product:hang", productFetcher(&calls, 0), productId, time.Minute); len(got) != 2 {
+		t.Errorf("got result %v", got)
+	}
+
+	// 回源沿用调用方 ctx 的截止时间
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	want, _ := ctx.Deadline()
+	fetcher := func(fctx context.Context) ([]*product, error) {
+		if got, ok := fctx.Deadline(); !ok || !got.Equal(want) {
+			t.Errorf("got deadline %v %v, want %v", got, ok, want)
+		}
+		return []*product{{Id: 1}}, nil
+	}
+	if _, err = cache.LoadIdMap(ctx, h, "product:deadline", fetcher, productId, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetIdMapStaleWhileRevalidate(t *testing.T) {
+	var calls int32
+	h := cache.NewLRU(0, 0)
+	fetcher := func(ctx context.Context) ([]*product, error) {
+		n := atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		return []*product{{Id: 1, Name: "v" + string(rune('0'+n))}}, nil
+	}
+	ctx := context.Background()
+	opt := cache.WithStaleWhileRevalidate(100 * time.Millisecond)
+
+	if got := cache.GetIdMap(ctx, h, "product:swr", fetcher, productId, time.Minute, opt); got[1].Name != "v1" {
+		t.Errorf("got result %v, want v1", got[1].Name)
+	}
+	if got := cache.GetIdMap(ctx, h, "product:swr", fetcher, productId, time.Minute, opt); got[1].Name != "v1" || atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("got result %v, want fresh v1", got[1].Name)
+	}
+
+	time.Sleep(120 * time.Millisecond)
+	start := time.Now()
+	if got := cache.GetIdMap(ctx, h, "product:swr", fetcher, productId, time.Minute, opt); got[1].Name != "v1" {
+		t.Errorf("got result %v, want stale v1", got[1].Name)
+	}
+	if time.Since(start) > 30*time.Millisecond {
+		t.Errorf("stale read blocked on refresh")
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	if got := cache.GetIdMap(ctx, h, "product:swr", fetcher, productId, time.Minute, opt); got[1].Name != "v2" {
+		t.Errorf("got result %v, want refreshed v2", got[1].Name)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("fetcher called %d times, want 2", got)
+	}
+}
+
+func TestGetIdMapEnableMetaOnExistingKey(t *testing.T) {
+	for _, opts := range [][]cache.Option{nil, {cache.WithCodec(cache.JSONCodec)}, {cache.WithCodec(cache.MsgpackCodec)}} {
+		var calls int32
+		h := cache.NewLRU(0, 0)
+		fetcher := productFetcher(&calls, 0)
+		ctx := context.Background()
+		cache.GetIdMap(ctx, h, "product:meta", fetcher, productId, time.Minute, opts...)
+
+		// 已有不带元数据的缓存时开启软过期，旧值按未命中处理
+		metaOpts := append([]cache.Option{cache.WithStaleWhileRevalidate(time.Second)}, opts...)
+		got, err := cache.LoadIdMap(ctx, h, "product:meta", fetcher, productId, time.Minute, metaOpts...)
+		if err != nil || len(got) != 2 || calls != 2 {
+			t.Errorf("got result %v %v with %d calls, want refetched map", got, err, calls)
+		}
+	}
+}
+
+func TestGetIdMapEarlyRefresh(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []cache.Option
+		calls int32
+	}{
+		{"no early refresh", []cache.Option{cache.WithStaleWhileRevalidate(time.Minute)}, 1},
+		{"early refresh", []cache.Option{cache.WithEarlyRefresh(1e9)}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			h := cache.NewLRU(0, 0)
+			fetcher := productFetcher(&calls, 10*time.Millisecond)
+			cache.GetIdMap(context.Background(), h, "product:xfetch", fetcher, productId, time.Hour, tt.opts...)
+			if got := cache.GetIdMap(context.Background(), h, "product:xfetch", fetcher, productId, time.Hour, tt.opts...); len(got) != 2 {
+				t.Errorf("got result %v", got)
+			}
+			time.Sleep(50 * time.Millisecond)
+			if got := atomic.LoadInt32(&calls); got != tt.calls {
+				t.Errorf("fetcher called %d times, want %d", got, tt.calls)
+			}
+		})
+	}
+}
+
+func TestGetIdMapRefreshNoExpiry(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []cache.Option
+		refresh bool
+	}{
+		{"stale while revalidate", []cache.Option{cache.WithStaleWhileRevalidate(time.Hour)}, false},
+		{"early refresh", []cache.Option{cache.WithEarlyRefresh(1)}, false},
+		{"short soft ttl", []cache.Option{cache.WithStaleWhileRevalidate(time.Nanosecond)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			h := cache.NewLRU(0, 0)
+			fetcher := productFetcher(&calls, 0)
+			// duration 为 0 表示永不过期，不应每次命中都刷新
+			for i := 0; i < 5; i++ {
+				if got := cache.GetIdMap(context.Background(), h, "product:forever", fetcher, productId, 0, tt.opts...); len(got) != 2 {
+					t.Errorf("got result %v", got)
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			if got := atomic.LoadInt32(&calls); (got > 1) != tt.refresh {
+				t.Errorf("fetcher called %d times, want refresh %v", got, tt.refresh)
+			}
+		})
+	}
+}
+
+// failBackend 写入总是失败的后端
+type failBackend struct {
+	*cache.LRU
+}
+
+func (failBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func TestLoadIdMapError(t *testing.T) {
+	dbErr := errors.New("db down")
+	fetcher := func(ctx context.Context) ([]*product, error) {
+		return nil, dbErr
+	}
+	got, err := cache.LoadIdMap(context.Background(), cache.NewLRU(0, 0), "product:load:err", fetcher, productId, time.Minute)
+	if got != nil || !errors.Is(err, dbErr) {
+		t.Errorf("got result %v, err %v, want nil and %v", got, err, dbErr)
+	}
+}
+
+func TestLoadIdMapEmpty(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []cache.Option
+		calls int32
+	}{
+		{"empty not cached", nil, 3},
+		{"empty cached", []cache.Option{cache.WithEmptyTTL(time.Minute)}, 1},
+		{"empty cached with meta", []cache.Option{cache.WithEmptyTTL(time.Minute), cache.WithStaleWhileRevalidate(time.Second)}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			h := cache.NewLRU(0, 0)
+			fetcher := func(ctx context.Context) ([]*product, error) {
+				atomic.AddInt32(&calls, 1)
+				return nil, nil
+			}
+			for i := 0; i < 3; i++ {
+				got, err := cache.LoadKeyMap(context.Background(), h, "product:empty", fetcher, productName, time.Hour, tt.opts...)
+				if err != nil || got == nil || len(got) != 0 {
+					t.Errorf("got result %v, err %v, want empty map", got, err)
+				}
+			}
+			if calls != tt.calls {
+				t.Errorf("fetcher called %d times, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestLoadIdMapEmptyTTLExpire(t *testing.T) {
+	var calls int32
+	h := cache.NewLRU(0, 0)
+	fetcher := func(ctx context.Context) ([]*product, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, nil
+	}
+	opt := cache.WithEmptyTTL(20 * time.Millisecond)
+	_, _ = cache.LoadIdMap(context.Background(), h, "product:empty:ttl", fetcher, productId, time.Hour, opt)
+	time.Sleep(30 * time.Millisecond)
+	_, _ = cache.LoadIdMap(context.Background(), h, "product:empty:ttl", fetcher, productId, time.Hour, opt)
+	if calls != 2 {
+		t.Errorf("fetcher called %d times, want 2", calls)
+	}
+}
+
+func TestLoadIdMapBackendError(t *testing.T) {
+	var calls int32
+	ctx := context.Background()
+	b := failBackend{cache.NewLRU(0, 0)}
+
+	got, err := cache.LoadIdMap(ctx, b, "product:backend", productFetcher(&calls, 0), productId, time.Minute)
+	if len(got) != 2 || !errors.Is(err, cache.ErrBackend) {
+		t.Errorf("got result %v, err %v, want data and ErrBackend", got, err)
+	}
+	if got := cache.GetIdMap(ctx, b, "product:backend", productFetcher(&calls, 0), productId, time.Minute); len(got) != 2 {
+		t.Errorf("got result %v, want data despite backend error", got)
+	}
+}
+
+func TestLoadIdMapCorruptEntry(t *testing.T) {
+	var calls int32
+	ctx := context.Background()
+	h := cache.NewLRU(0, 0)
+	_ = h.Set(ctx, "product:corrupt", []byte("not json"), 0)
+
+	got, err := cache.LoadIdMap(ctx, h, "product:corrupt", productFetcher(&calls, 0), productId, time.Minute)
+	if len(got) != 2 || !errors.Is(err, cache.ErrBackend) {
+		t.Errorf("got result %v, err %v, want data and ErrBackend", got, err)
+	}
+	got, err = cache.LoadIdMap(ctx, h, "product:corrupt", productFetcher(&calls, 0), productId, time.Minute)
+	if len(got) != 2 || err != nil || calls != 1 {
+		t.Errorf("got result %v, err %v, calls %d, want entry rewritten", got, err, calls)
+	}
+}
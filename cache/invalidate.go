@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const tagKeyPrefix = "gotool:tag:"
+
+// maxFallbackTagKeys 未实现 TagStore 时单个 tag 最多关联的 key 数，每次注册都会整体重写 key 列表
+const maxFallbackTagKeys = 1000
+
+// TagStore 后端可实现该接口以原子方式维护 tag 与 key 的关联（如 Redis 的 SADD / SMEMBERS + DEL）
+// 未实现时退化为将 tag 下的 key 列表以 JSON 存入后端：读改写仅在本进程内加锁，多实例并发注册可能丢失；
+// 列表中的 key 按各自的过期时间清理，列表本身在最晚的 key 过期后过期；单个 tag 超过 1000 个 key 时注册失败，
+// 此类场景（如 GetByIds 的大表）应使用实现了 TagStore 的后端
+type TagStore interface {
+	// AddTag 将 keys 注册到 tag 下
+	AddTag(ctx context.Context, tag string, keys ...string) error
+	// PopTag 返回 tag 下的所有 key 并清空该 tag
+	PopTag(ctx context.Context, tag string) ([]string, error)
+}
+
+// Invalidate 删除缓存 key，下次读取时重新回源
+func Invalidate(ctx cCtx, cacheIns Backend, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := cacheIns.Delete(ctx, keys...); err != nil {
+		return fmt.Errorf("%w: delete: %w", ErrBackend, err)
+	}
+	return nil
+}
+
+// InvalidateTag 删除通过 WithTags 注册在这些 tag 下的所有缓存 key
+func InvalidateTag(ctx cCtx, cacheIns Backend, tags ...string) error {
+	for _, tag := range tags {
+		keys, err := popTag(ctx, cacheIns, tag)
+		if err != nil {
+			return fmt.Errorf("%w: pop tag %s: %w", ErrBackend, tag, err)
+		}
+		if err = Invalidate(ctx, cacheIns, keys...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tagKeys 将 keys 注册到所有 tags 下，ttl 为这些 key 的过期时间
+func tagKeys(ctx cCtx, cacheIns Backend, tags []string, ttl time.Duration, keys ...string) error {
+	for _, tag := range tags {
+		if err := addTag(ctx, cacheIns, tag, ttl, keys...); err != nil {
+			return fmt.Errorf("%w: add tag %s: %w", ErrBackend, tag, err)
+		}
+	}
+	return nil
+}
+
+// tagMu 保护未实现 TagStore 的后端上 tag 列表的读改写
+var tagMu sync.Mutex
+
+// tagDelegate 自身不保存 tag 的后端（如 Tiered），返回实际保存 tag 的后端
+type tagDelegate interface {
+	tagBackend() Backend
+}
+
+// tagEntry 退化路径下 tag 列表的一项，Exp 为 key 的过期时间（unix 毫秒），0 表示不过期
+type tagEntry struct {
+	Key string `json:"k"`
+	Exp int64  `json:"e,omitempty"`
+}
+
+func addTag(ctx cCtx, cacheIns Backend, tag string, ttl time.Duration, keys ...string) error {
+	if d, ok := cacheIns.(tagDelegate); ok {
+		cacheIns = d.tagBackend()
+	}
+	if ts, ok := cacheIns.(TagStore); ok {
+		return ts.AddTag(ctx, tag, keys...)
+	}
+
+	tagMu.Lock()
+	defer tagMu.Unlock()
+	existing, err := readTag(ctx, cacheIns, tag)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UnixMilli()
+	var exp int64
+	if ttl > 0 {
+		exp = now + int64((ttl+time.Millisecond-1)/time.Millisecond)
+	}
+
+	// 清理已过期的 key，并更新或追加本次注册的 key
+	entries := make([]tagEntry, 0, len(existing)+len(keys))
+	index := make(map[string]int, len(existing)+len(keys))
+	for _, e := range existing {
+		if e.Exp == 0 || e.Exp > now {
+			index[e.Key] = len(entries)
+			entries = append(entries, e)
+		}
+	}
+	for _, k := range keys {
+		i, ok := index[k]
+		if !ok {
+			index[k] = len(entries)
+			entries = append(entries, tagEntry{Key: k, Exp: exp})
+		} else if entries[i].Exp != 0 && (exp == 0 || exp > entries[i].Exp) {
+			entries[i].Exp = exp
+		}
+	}
+	if len(entries) > maxFallbackTagKeys {
+		return fmt.Errorf("cache: tag %s exceeds %d keys, use a backend implementing TagStore", tag, maxFallbackTagKeys)
+	}
+
+	var listTTL time.Duration
+	for _, e := range entries {
+		if e.Exp == 0 {
+			listTTL = 0
+			break
+		}
+		if d := time.Duration(e.Exp-now) * time.Millisecond; d > listTTL {
+			listTTL = d
+		}
+	}
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return cacheIns.Set(ctx, tagKeyPrefix+tag, b, listTTL)
+}
+
+func popTag(ctx cCtx, cacheIns Backend, tag string) ([]string, error) {
+	if d, ok := cacheIns.(tagDelegate); ok {
+		cacheIns = d.tagBackend()
+	}
+	if ts, ok := cacheIns.(TagStore); ok {
+		return ts.PopTag(ctx, tag)
+	}
+
+	tagMu.Lock()
+	defer tagMu.Unlock()
+	entries, err := readTag(ctx, cacheIns, tag)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+	}
+	return keys, cacheIns.Delete(ctx, tagKeyPrefix+tag)
+}
+
+func readTag(ctx cCtx, cacheIns Backend, tag string) ([]tagEntry, error) {
+	b, ok, err := cacheIns.Get(ctx, tagKeyPrefix+tag)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var entries []tagEntry
+	if err = json.Unmarshal(b, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
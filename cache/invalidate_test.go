@@ -0,0 +1,149 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/supernarsi/gotool/cache"
+)
+
+// plainBackend 只实现 Backend 的后端，用于覆盖未实现 TagStore / MultiGetter 的退化路径
+type plainBackend struct {
+	b cache.Backend
+}
+
+func (p plainBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return p.b.Get(ctx, key)
+}
+
+func (p plainBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return p.b.Set(ctx, key, value, ttl)
+}
+
+func (p plainBackend) Delete(ctx context.Context, keys ...string) error {
+	return p.b.Delete(ctx, keys...)
+}
+
+func TestInvalidate(t *testing.T) {
+	var calls int32
+	ctx := context.Background()
+	h := cache.NewLRU(0, 0)
+
+	cache.GetIdMap(ctx, h, "product:id", productFetcher(&calls, 0), productId, time.Hour)
+	if err := cache.Invalidate(ctx, h, "product:id", "missing"); err != nil {
+		t.Fatal(err)
+	}
+	cache.GetIdMap(ctx, h, "product:id", productFetcher(&calls, 0), productId, time.Hour)
+	if calls != 2 {
+		t.Errorf("fetcher called %d times, want 2", calls)
+	}
+}
+
+func TestInvalidateTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend cache.Backend
+	}{
+		{"tag store", cache.NewLRU(0, 0)},
+		{"fallback", plainBackend{cache.NewLRU(0, 0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			ctx := context.Background()
+			fetcher := productFetcher(&calls, 0)
+			r := &batchRecorder{db: map[uint]string{1: "apple"}}
+			tag := cache.WithTags("product")
+			load := func() {
+				cache.GetIdMap(ctx, tt.backend, "product:id", fetcher, productId, time.Hour, tag)
+				cache.GetKeyMap(ctx, tt.backend, "product:name", fetcher, productName, time.Hour, tag)
+				cache.GetKeyMap(ctx, tt.backend, "product:other", fetcher, productName, time.Hour)
+				cache.GetByIds(ctx, tt.backend, "product:", []uint{1}, r.fetch, productId, time.Hour, tag)
+			}
+
+			load()
+			load()
+			if got := atomic.LoadInt32(&calls); got != 3 || len(r.calls) != 1 {
+				t.Errorf("fetcher called %d / %d times, want 3 / 1", got, len(r.calls))
+			}
+
+			if err := cache.InvalidateTag(ctx, tt.backend, "product"); err != nil {
+				t.Fatal(err)
+			}
+			load()
+			if got := atomic.LoadInt32(&calls); got != 5 || len(r.calls) != 2 {
+				t.Errorf("fetcher called %d / %d times, want 5 / 2", got, len(r.calls))
+			}
+
+			// 清空后的 tag 重新注册
+			if err := cache.InvalidateTag(ctx, tt.backend, "product", "unknown"); err != nil {
+				t.Fatal(err)
+			}
+			load()
+			if got := atomic.LoadInt32(&calls); got != 7 {
+				t.Errorf("fetcher called %d times, want 7", got)
+			}
+		})
+	}
+}
+
+func TestInvalidateTagTiered(t *testing.T) {
+	var calls int32
+	ctx := context.Background()
+	l2 := cache.NewLRU(0, 0)
+	bus := cache.NewMemPubSub()
+	a := newTiered(t, l2, bus)
+	b := newTiered(t, l2, bus)
+	tag := cache.WithTags("product")
+
+	cache.GetIdMap(ctx, a, "product:id", productFetcher(&calls, 0), productId, time.Hour, tag)
+	cache.GetIdMap(ctx, b, "product:id", productFetcher(&calls, 0), productId, time.Hour, tag)
+	if err := cache.InvalidateTag(ctx, a, "product"); err != nil {
+		t.Fatal(err)
+	}
+	cache.GetIdMap(ctx, b, "product:id", productFetcher(&calls, 0), productId, time.Hour, tag)
+	if calls != 2 {
+		t.Errorf("fetcher called %d times, want 2", calls)
+	}
+}
+
+func TestInvalidateTagFallbackLimits(t *testing.T) {
+	ctx := context.Background()
+	lru := cache.NewLRU(0, 0)
+	h := plainBackend{lru}
+	tag := cache.WithTags("short")
+
+	var calls int32
+	if _, err := cache.LoadIdMap(ctx, h, "product:short", productFetcher(&calls, 0), productId, 50*time.Millisecond, tag); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := lru.Get(ctx, "gotool:tag:short"); !ok {
+		t.Fatal("tag list should be stored")
+	}
+	// tag 列表在最晚的 key 过期后过期
+	time.Sleep(70 * time.Millisecond)
+	if _, ok, _ := lru.Get(ctx, "gotool:tag:short"); ok {
+		t.Error("tag list should expire with its keys")
+	}
+
+	// 超过上限时注册失败，返回 ErrBackend
+	ids := make([]uint, 1001)
+	for i := range ids {
+		ids[i] = uint(i + 1)
+	}
+	fetch := func(ctx context.Context, missingIds []uint) ([]*product, error) {
+		items := make([]*product, len(missingIds))
+		for i, id := range missingIds {
+			items[i] = &product{Id: id, Name: strconv.Itoa(int(id))}
+		}
+		return items, nil
+	}
+	got, err := cache.LoadByIds(ctx, h, "product:", ids, fetch, productId, time.Hour, cache.WithTags("big"))
+	if len(got) != len(ids) || !errors.Is(err, cache.ErrBackend) {
+		t.Errorf("got %d items, err %v, want %d items and %v", len(got), err, len(ids), cache.ErrBackend)
+	}
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// keyPartEscaper 转义 part 中的分隔符，使 ("a:b", "c") 与 ("a", "b:c") 生成不同的 key
+var keyPartEscaper = strings.NewReplacer("%", "%25", ":", "%3A")
+
+// KeyBuilder 为值类型 T 生成带命名空间的缓存 key，格式为 prefix:v{version}:part1:part2
+// 缓存值结构变更时递增 version，新代码不会读到旧结构的数据；通过 Get 读取时值类型固定为 T
+type KeyBuilder[T any] struct {
+	ns string
+}
+
+// NewKeyBuilder 创建 key 生成器
+func NewKeyBuilder[T any](prefix string, version uint) KeyBuilder[T] {
+	return KeyBuilder[T]{ns: prefix + ":v" + strconv.FormatUint(uint64(version), 10) + ":"}
+}
+
+// Key 用 parts 拼接缓存 key，part 中的 ":" 与 "%" 按 URL 编码转义
+func (b KeyBuilder[T]) Key(parts ...interface{}) string {
+	strs := make([]string, len(parts))
+	for i, p := range parts {
+		strs[i] = keyPartEscaper.Replace(fmt.Sprint(p))
+	}
+	return b.ns + strings.Join(strs, ":")
+}
+
+// Get 以 Key(parts...) 为 key 调用 Get[T]
+func (b KeyBuilder[T]) Get(ctx cCtx, cacheIns Backend, parts []interface{}, load LoaderFunc[T], ttl time.Duration, opts ...Option) (T, error) {
+	return Get(ctx, cacheIns, b.Key(parts...), load, ttl, opts...)
+}
+
+// Prefix 命名空间前缀，可作为 GetByIds 的 keyPrefix
+func (b KeyBuilder[T]) Prefix() string {
+	return b.ns
+}
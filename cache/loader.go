@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ErrBackend 缓存后端读写或编解码失败；伴随该错误返回的数据来自数据源，仍然可用
+var ErrBackend = errors.New("cache: backend error")
+
+// metaMarker 标记缓存值为 cacheEntry；未开启相关选项时写入的值解码后没有该标记，按未命中处理
+const metaMarker uint8 = 1
+
+// cacheEntry 开启软过期或提前刷新时实际写入缓存的结构
+type cacheEntry[V any] struct {
+	Meta    uint8 `json:"_meta"`
+	Data    V     `json:"data"`
+	Refresh int64 `json:"refresh"` // 软过期时间点，unix 纳秒；为 0 时不刷新
+	Delta   int64 `json:"delta"`   // 上次回源耗时，纳秒
+}
+
+// fillResult 一次回源的结果，err 为写缓存时的后端错误
+type fillResult[V any] struct {
+	v   V
+	err error
+}
+
+// loader 单个缓存 key 的读穿加载逻辑
+type loader[V any] struct {
+	cacheIns Backend
+	key      string
+	fetch    func(ctx cCtx) (V, error)
+	empty    func(v V) bool
+	duration time.Duration
+	opts     loadOptions
+}
+
+// load 读缓存，未命中时合并回源；命中但需刷新时返回当前值并在后台刷新
+// 回源失败时返回回源错误；仅缓存后端出错时返回数据及包装了 ErrBackend 的错误
+func (l *loader[V]) load(ctx cCtx) (V, error) {
+	fk := newFlightKey[V](l.cacheIns, l.key)
+	v, refresh, ok, getErr := l.get(ctx)
+	if ok {
+		l.opts.obs.OnHit(l.key)
+		if refresh {
+			loadGroup.Go(ctx, fk, l.opts.loadTimeout, func(ctx cCtx) (interface{}, error) {
+				return l.fill(ctx)
+			})
+		}
+		return v, nil
+	}
+	l.opts.obs.OnMiss(l.key)
+
+	val, err := loadGroup.Do(ctx, fk, l.opts.loadTimeout, func(ctx cCtx) (interface{}, error) {
+		// 排队期间可能已有其他回源写入缓存；此处的读取错误已在上面报告过，不再通知观测者
+		quiet := *l
+		quiet.opts.obs = NopObserver{}
+		if v, refresh, ok, _ := quiet.get(ctx); ok && !refresh {
+			return &fillResult[V]{v: v}, nil
+		}
+		return l.fill(ctx)
+	})
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	res := val.(*fillResult[V])
+	return res.v, errors.Join(getErr, res.err)
+}
+
+// get 读缓存，返回值、是否需要刷新、是否命中以及后端错误
+func (l *loader[V]) get(ctx cCtx) (V, bool, bool, error) {
+	if !l.opts.withMeta() {
+		var v V
+		ok, err := getFromCache(ctx, &l.opts, l.cacheIns, l.key, &v)
+		return v, false, ok, err
+	}
+
+	var ent cacheEntry[V]
+	ok, err := getFromCache(ctx, &l.opts, l.cacheIns, l.key, &ent)
+	if !ok || ent.Meta != metaMarker {
+		var zero V
+		return zero, false, false, err
+	}
+	return ent.Data, l.needRefresh(&ent, time.Now()), true, nil
+}
+
+// needRefresh 已过软过期时间，或按 XFetch 概率提前刷新
+// XFetch: now - delta * beta * ln(rand) >= expiry
+func (l *loader[V]) needRefresh(ent *cacheEntry[V], now time.Time) bool {
+	if ent.Refresh == 0 {
+		return false
+	}
+	nowNano := now.UnixNano()
+	if nowNano >= ent.Refresh {
+		return true
+	}
+	if l.opts.beta <= 0 || ent.Delta <= 0 {
+		return false
+	}
+	gap := float64(ent.Delta) * l.opts.beta * -math.Log(1-rand.Float64())
+	return float64(nowNano)+gap >= float64(ent.Refresh)
+}
+
+// fill 回源并写入缓存
+// 空结果仅在设置了 WithEmptyTTL 时以该时长缓存
+func (l *loader[V]) fill(ctx cCtx) (*fillResult[V], error) {
+	start := time.Now()
+	v, err := l.fetch(ctx)
+	l.opts.obs.OnFetch(l.key, time.Since(start))
+	if err != nil {
+		l.opts.obs.OnFetchError(l.key, err)
+		return nil, err
+	}
+
+	duration := l.duration
+	if l.empty(v) {
+		if l.opts.emptyTTL <= 0 {
+			return &fillResult[V]{v: v}, nil
+		}
+		duration = l.opts.emptyTTL
+	}
+
+	if !l.opts.withMeta() {
+		return &fillResult[V]{v: v, err: l.store(ctx, v, duration)}, nil
+	}
+
+	// duration <= 0 表示永不过期，此时仅按 softTTL 刷新，未设置 softTTL 时不刷新
+	refreshAfter := duration
+	if l.opts.softTTL > 0 && (refreshAfter <= 0 || l.opts.softTTL < refreshAfter) {
+		refreshAfter = l.opts.softTTL
+	}
+	now := time.Now()
+	ent := cacheEntry[V]{
+		Meta:  metaMarker,
+		Data:  v,
+		Delta: now.Sub(start).Nanoseconds(),
+	}
+	if refreshAfter > 0 {
+		ent.Refresh = now.Add(refreshAfter).UnixNano()
+	}
+	return &fillResult[V]{v: v, err: l.store(ctx, ent, duration)}, nil
+}
+
+// store 写入缓存并注册 tag
+func (l *loader[V]) store(ctx cCtx, value interface{}, duration time.Duration) error {
+	if err := setToCache(ctx, &l.opts, l.cacheIns, l.key, value, duration); err != nil {
+		return err
+	}
+	return tagKeys(ctx, l.cacheIns, l.opts.tags, duration, l.key)
+}
+
+// getFromCache 读取并解码缓存，返回是否命中；读取或解码失败按未命中处理并返回错误，版本不一致按未命中处理
+func getFromCache[T any](ctx cCtx, o *loadOptions, cacheIns Backend, key string, dest *T) (bool, error) {
+	cacheVal, ok, err := cacheIns.Get(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("%w: get %s: %w", ErrBackend, key, err)
+	}
+	if !ok {
+		return false, nil
+	}
+	if err = o.ser.unmarshal(cacheVal, dest); err != nil {
+		if errors.Is(err, errVersionMismatch) {
+			return false, nil
+		}
+		o.obs.OnDecodeError(key, err)
+		return false, fmt.Errorf("%w: decode %s: %w", ErrBackend, key, err)
+	}
+	return true, nil
+}
+
+func setToCache(ctx cCtx, o *loadOptions, cacheIns Backend, key string, value interface{}, duration time.Duration) error {
+	b, err := o.ser.marshal(value)
+	if err != nil {
+		o.obs.OnEncodeError(key, err)
+		return fmt.Errorf("%w: encode %s: %w", ErrBackend, key, err)
+	}
+	if err = cacheIns.Set(ctx, key, b, duration); err != nil {
+		return fmt.Errorf("%w: set %s: %w", ErrBackend, key, err)
+	}
+	o.obs.OnWrite(key, len(b))
+	return nil
+}
@@ -0,0 +1,185 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU 进程内按容量淘汰的缓存后端，支持 TTL，可并发使用
+type LRU struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	ll         *list.List
+	items      map[string]*list.Element
+	tags       map[string]map[string]struct{}
+}
+
+type lruItem struct {
+	key      string
+	value    []byte
+	expireAt time.Time           // 零值表示不过期
+	tags     map[string]struct{} // 该 key 注册的 tag，移除时从 tag 中一并删除
+}
+
+// NewLRU 创建 LRU 缓存
+// - maxEntries: 最大条目数，<= 0 表示不限制
+// - maxBytes: 所有值的总字节数上限，<= 0 表示不限制
+func NewLRU(maxEntries int, maxBytes int64) *LRU {
+	return &LRU{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		tags:       make(map[string]map[string]struct{}),
+	}
+}
+
+// Get 读取 key，过期的 key 视为不存在并被移除
+func (c *LRU) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ele, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	item := ele.Value.(*lruItem)
+	if !item.expireAt.IsZero() && !time.Now().Before(item.expireAt) {
+		c.removeElement(ele)
+		return nil, false, nil
+	}
+	c.ll.MoveToFront(ele)
+	return item.value, true, nil
+}
+
+// GetMulti 批量读取，返回结果中只包含存在且未过期的 key
+func (c *LRU) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if val, ok, _ := c.Get(ctx, key); ok {
+			values[key] = val
+		}
+	}
+	return values, nil
+}
+
+// Set 写入 key，ttl <= 0 表示不过期；单个值超过 maxBytes 时不写入
+func (c *LRU) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	val := make([]byte, len(value))
+	copy(val, value)
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 覆盖写入时保留已注册的 tag
+	var tags map[string]struct{}
+	if ele, ok := c.items[key]; ok {
+		tags = ele.Value.(*lruItem).tags
+		ele.Value.(*lruItem).tags = nil
+		c.removeElement(ele)
+	}
+	if c.maxBytes > 0 && int64(len(val)) > c.maxBytes {
+		c.untag(key, tags)
+		return nil
+	}
+
+	c.items[key] = c.ll.PushFront(&lruItem{key: key, value: val, expireAt: expireAt, tags: tags})
+	c.bytes += int64(len(val))
+	for (c.maxEntries > 0 && c.ll.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.removeElement(c.ll.Back())
+	}
+	return nil
+}
+
+// Delete 删除 key
+func (c *LRU) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if ele, ok := c.items[key]; ok {
+			c.removeElement(ele)
+		}
+	}
+	return nil
+}
+
+// AddTag 将 keys 注册到 tag 下，不存在的 key 忽略；key 被删除、淘汰或过期时自动从 tag 中移除
+func (c *LRU) AddTag(ctx context.Context, tag string, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		ele, ok := c.items[key]
+		if !ok {
+			continue
+		}
+		item := ele.Value.(*lruItem)
+		if item.tags == nil {
+			item.tags = make(map[string]struct{})
+		}
+		item.tags[tag] = struct{}{}
+		set, ok := c.tags[tag]
+		if !ok {
+			set = make(map[string]struct{})
+			c.tags[tag] = set
+		}
+		set[key] = struct{}{}
+	}
+	return nil
+}
+
+// PopTag 返回 tag 下的所有 key 并清空该 tag
+func (c *LRU) PopTag(ctx context.Context, tag string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	set := c.tags[tag]
+	delete(c.tags, tag)
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+		if ele, ok := c.items[key]; ok {
+			delete(ele.Value.(*lruItem).tags, tag)
+		}
+	}
+	return keys, nil
+}
+
+// Len 当前条目数（含尚未清理的过期条目）
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// TagLen 当前注册了 key 的 tag 数
+func (c *LRU) TagLen() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.tags)
+}
+
+func (c *LRU) removeElement(ele *list.Element) {
+	item := c.ll.Remove(ele).(*lruItem)
+	delete(c.items, item.key)
+	c.bytes -= int64(len(item.value))
+	c.untag(item.key, item.tags)
+}
+
+// untag 从 tags 中移除 key，tag 为空时一并删除
+func (c *LRU) untag(key string, tags map[string]struct{}) {
+	for tag := range tags {
+		if set, ok := c.tags[tag]; ok {
+			delete(set, key)
+			if len(set) == 0 {
+				delete(c.tags, tag)
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package cache_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/supernarsi/gotool/cache"
+)
+
+func TestLRUGetSet(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRU(0, 0)
+
+	if _, ok, err := c.Get(ctx, "k"); ok || err != nil {
+		t.Errorf("got ok %v, err %v, want miss", ok, err)
+	}
+
+	buf := []byte("v1")
+	_ = c.Set(ctx, "k", buf, 0)
+	buf[0] = 'x'
+	if got, ok, _ := c.Get(ctx, "k"); !ok || string(got) != "v1" {
+		t.Errorf("got result %s, want v1", got)
+	}
+
+	_ = c.Set(ctx, "k", []byte("v2"), 0)
+	if got, _, _ := c.Get(ctx, "k"); string(got) != "v2" {
+		t.Errorf("got result %s, want v2", got)
+	}
+
+	_ = c.Delete(ctx, "k", "missing")
+	if _, ok, _ := c.Get(ctx, "k"); ok {
+		t.Error("key should be deleted")
+	}
+}
+
+func TestLRUTTL(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRU(0, 0)
+	_ = c.Set(ctx, "short", []byte("1"), 20*time.Millisecond)
+	_ = c.Set(ctx, "forever", []byte("2"), 0)
+
+	time.Sleep(30 * time.Millisecond)
+	if _, ok, _ := c.Get(ctx, "short"); ok {
+		t.Error("short should be expired")
+	}
+	if _, ok, _ := c.Get(ctx, "forever"); !ok {
+		t.Error("forever should not expire")
+	}
+	if c.Len() != 1 {
+		t.Errorf("got len %d, want 1", c.Len())
+	}
+}
+
+func TestLRUEvict(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxEntries int
+		maxBytes   int64
+		want       []string
+	}{
+		{"by entries", 2, 0, []string{"a", "c"}},
+		{"by bytes", 0, 6, []string{"a", "c"}},
+		{"unbounded", 0, 0, []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := cache.NewLRU(tt.maxEntries, tt.maxBytes)
+			_ = c.Set(ctx, "a", []byte("aaa"), 0)
+			_ = c.Set(ctx, "b", []byte("bbb"), 0)
+			_, _, _ = c.Get(ctx, "a")
+			_ = c.Set(ctx, "c", []byte("ccc"), 0)
+
+			if c.Len() != len(tt.want) {
+				t.Errorf("got len %d, want %d", c.Len(), len(tt.want))
+			}
+			for _, k := range tt.want {
+				if _, ok, _ := c.Get(ctx, k); !ok {
+					t.Errorf("key %s should exist", k)
+				}
+			}
+		})
+	}
+}
+
+func TestLRUTags(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRU(2, 0)
+	_ = c.Set(ctx, "a", []byte("1"), 0)
+	_ = c.Set(ctx, "b", []byte("2"), 20*time.Millisecond)
+	_ = c.AddTag(ctx, "t1", "a", "b", "missing")
+	_ = c.AddTag(ctx, "t2", "a")
+
+	// 覆盖写入保留 tag
+	_ = c.Set(ctx, "a", []byte("3"), 0)
+	if keys, _ := c.PopTag(ctx, "t2"); len(keys) != 1 || keys[0] != "a" {
+		t.Errorf("got result %v, want [a]", keys)
+	}
+
+	// 过期、淘汰与删除的 key 从 tag 中移除
+	time.Sleep(30 * time.Millisecond)
+	_, _, _ = c.Get(ctx, "b")
+	_ = c.Set(ctx, "c", []byte("4"), 0)
+	_ = c.AddTag(ctx, "t3", "c")
+	_ = c.Set(ctx, "d", []byte("5"), 0) // 淘汰 a
+	if got := c.TagLen(); got != 1 {
+		t.Errorf("got tag len %d, want 1", got)
+	}
+	_ = c.Delete(ctx, "c")
+	if got := c.TagLen(); got != 0 {
+		t.Errorf("got tag len %d, want 0", got)
+	}
+}
+
+func TestLRUOversizedValue(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRU(0, 4)
+	_ = c.Set(ctx, "a", []byte("aa"), 0)
+	_ = c.Set(ctx, "big", []byte("too large"), 0)
+	if _, ok, _ := c.Get(ctx, "big"); ok {
+		t.Error("oversized value should not be stored")
+	}
+	if _, ok, _ := c.Get(ctx, "a"); !ok {
+		t.Error("existing key should be kept")
+	}
+}
+
+func TestLRUConcurrent(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRU(100, 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				key := strconv.Itoa((i * j) % 150)
+				_ = c.Set(ctx, key, []byte(key), time.Minute)
+				_, _, _ = c.Get(ctx, key)
+				if j%10 == 0 {
+					_ = c.Delete(ctx, key)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if c.Len() > 100 {
+		t.Errorf("got len %d, want <= 100", c.Len())
+	}
+}
@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// MultiGetter 支持批量读取的后端可实现该接口，GetByIds 会优先使用
+type MultiGetter interface {
+	// GetMulti 批量读取，返回结果中只包含存在的 key
+	GetMulti(ctx context.Context, keys []string) (map[string][]byte, error)
+}
+
+// BatchFetcherFunc 按 id 批量回源
+type BatchFetcherFunc[T any] func(ctx cCtx, missingIds []uint) ([]*T, error)
+
+// GetByIds 按 id 逐条缓存的批量读取，key 为 keyPrefix + id
+// 先批量读取缓存，仅对未命中的 id 调用一次 batchFetcher，并将结果逐条写回；回源失败时返回 nil
+// 设置 WithEmptyTTL 时，数据源中不存在的 id 也会被缓存，避免重复回源
+func GetByIds[T any](ctx cCtx, cacheIns Backend, keyPrefix string, ids []uint, batchFetcher BatchFetcherFunc[T], getId GetIdFunc[T], ttl time.Duration, opts ...Option) map[uint]*T {
+	result, err := LoadByIds(ctx, cacheIns, keyPrefix, ids, batchFetcher, getId, ttl, opts...)
+	if err != nil && !errors.Is(err, ErrBackend) {
+		return nil
+	}
+	return result
+}
+
+// LoadByIds 同 GetByIds，但返回错误，错误语义与 LoadIdMap 一致
+func LoadByIds[T any](ctx cCtx, cacheIns Backend, keyPrefix string, ids []uint, batchFetcher BatchFetcherFunc[T], getId GetIdFunc[T], ttl time.Duration, opts ...Option) (map[uint]*T, error) {
+	o := newLoadOptions(opts)
+	ids = uniqueIds(ids)
+	result := make(map[uint]*T, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = itemKey(keyPrefix, id)
+	}
+	cached, err := getMulti(ctx, cacheIns, keys)
+	errs := []error{err}
+
+	missing := make([]uint, 0, len(ids))
+	for i, id := range ids {
+		b, ok := cached[keys[i]]
+		if !ok {
+			o.obs.OnMiss(keys[i])
+			missing = append(missing, id)
+			continue
+		}
+		var item *T
+		if err = o.ser.unmarshal(b, &item); errors.Is(err, errVersionMismatch) {
+			o.obs.OnMiss(keys[i])
+			missing = append(missing, id)
+			continue
+		} else if err != nil {
+			o.obs.OnDecodeError(keys[i], err)
+			o.obs.OnMiss(keys[i])
+			errs = append(errs, fmt.Errorf("%w: decode %s: %w", ErrBackend, keys[i], err))
+			missing = append(missing, id)
+			continue
+		}
+		o.obs.OnHit(keys[i])
+		// 负缓存的 id 解码为 nil
+		if item != nil {
+			result[id] = item
+		}
+	}
+	if len(missing) == 0 {
+		return result, errors.Join(errs...)
+	}
+
+	start := time.Now()
+	items, err := batchFetcher(ctx, missing)
+	o.obs.OnFetch(keyPrefix, time.Since(start))
+	if err != nil {
+		o.obs.OnFetchError(keyPrefix, err)
+		return nil, err
+	}
+	written := make([]string, 0, len(missing))
+	for _, item := range items {
+		id := getId(item)
+		result[id] = item
+		key := itemKey(keyPrefix, id)
+		if err = setToCache(ctx, &o, cacheIns, key, item, ttl); err != nil {
+			errs = append(errs, err)
+		} else {
+			written = append(written, key)
+		}
+	}
+	if o.emptyTTL > 0 {
+		for _, id := range missing {
+			if _, ok := result[id]; ok {
+				continue
+			}
+			key := itemKey(keyPrefix, id)
+			if err = setToCache(ctx, &o, cacheIns, key, (*T)(nil), o.emptyTTL); err != nil {
+				errs = append(errs, err)
+			} else {
+				written = append(written, key)
+			}
+		}
+	}
+	if len(written) > 0 {
+		tagTTL := ttl
+		if ttl > 0 && o.emptyTTL > ttl {
+			tagTTL = o.emptyTTL
+		}
+		errs = append(errs, tagKeys(ctx, cacheIns, o.tags, tagTTL, written...))
+	}
+	return result, errors.Join(errs...)
+}
+
+// getMulti 批量读取，后端未实现 MultiGetter 时逐个读取
+func getMulti(ctx cCtx, cacheIns Backend, keys []string) (map[string][]byte, error) {
+	if mg, ok := cacheIns.(MultiGetter); ok {
+		values, err := mg.GetMulti(ctx, keys)
+		if err != nil {
+			return nil, fmt.Errorf("%w: get multi: %w", ErrBackend, err)
+		}
+		return values, nil
+	}
+
+	values := make(map[string][]byte, len(keys))
+	var errs []error
+	for _, key := range keys {
+		val, ok, err := cacheIns.Get(ctx, key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: get %s: %w", ErrBackend, key, err))
+			continue
+		}
+		if ok {
+			values[key] = val
+		}
+	}
+	return values, errors.Join(errs...)
+}
+
+func itemKey(keyPrefix string, id uint) string {
+	return keyPrefix + strconv.FormatUint(uint64(id), 10)
+}
+
+func uniqueIds(ids []uint) []uint {
+	seen := make(map[uint]struct{}, len(ids))
+	res := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			res = append(res, id)
+		}
+	}
+	return res
+}
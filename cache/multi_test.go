@@ -0,0 +1,95 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/supernarsi/gotool/cache"
+)
+
+type batchRecorder struct {
+	calls [][]uint
+	db    map[uint]string
+}
+
+func (r *batchRecorder) fetch(ctx context.Context, missingIds []uint) ([]*product, error) {
+	ids := append([]uint(nil), missingIds...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	r.calls = append(r.calls, ids)
+
+	items := make([]*product, 0, len(missingIds))
+	for _, id := range missingIds {
+		if name, ok := r.db[id]; ok {
+			items = append(items, &product{Id: id, Name: name})
+		}
+	}
+	return items, nil
+}
+
+func TestGetByIds(t *testing.T) {
+	ctx := context.Background()
+	h := cache.NewLRU(0, 0)
+	r := &batchRecorder{db: map[uint]string{1: "apple", 2: "banana", 3: "cherry"}}
+
+	got := cache.GetByIds(ctx, h, "product:", []uint{1, 2, 2}, r.fetch, productId, time.Minute)
+	if len(got) != 2 || got[1].Name != "apple" || got[2].Name != "banana" {
+		t.Errorf("got result %v", got)
+	}
+
+	got = cache.GetByIds(ctx, h, "product:", []uint{1, 2, 3, 4}, r.fetch, productId, time.Minute)
+	if len(got) != 3 || got[3].Name != "cherry" {
+		t.Errorf("got result %v", got)
+	}
+
+	want := [][]uint{{1, 2}, {3, 4}}
+	if !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("got fetch calls %v, want %v", r.calls, want)
+	}
+	if _, ok, _ := h.Get(ctx, "product:3"); !ok {
+		t.Error("product:3 should be cached")
+	}
+}
+
+func TestGetByIdsNegative(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []cache.Option
+		want [][]uint
+	}{
+		{"missing not cached", nil, [][]uint{{1, 9}, {9}}},
+		{"missing cached", []cache.Option{cache.WithEmptyTTL(time.Minute)}, [][]uint{{1, 9}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := cache.NewHandlerBackend(newMemHandler())
+			r := &batchRecorder{db: map[uint]string{1: "apple"}}
+			for i := 0; i < 2; i++ {
+				got, err := cache.LoadByIds(context.Background(), h, "product:", []uint{1, 9}, r.fetch, productId, time.Minute, tt.opts...)
+				if err != nil || len(got) != 1 || got[1].Name != "apple" {
+					t.Errorf("got result %v, err %v", got, err)
+				}
+			}
+			if !reflect.DeepEqual(r.calls, tt.want) {
+				t.Errorf("got fetch calls %v, want %v", r.calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetByIdsFetchError(t *testing.T) {
+	dbErr := errors.New("db down")
+	fetcher := func(ctx context.Context, missingIds []uint) ([]*product, error) {
+		return nil, dbErr
+	}
+	got, err := cache.LoadByIds(context.Background(), cache.NewLRU(0, 0), "product:", []uint{1}, fetcher, productId, time.Minute)
+	if got != nil || !errors.Is(err, dbErr) {
+		t.Errorf("got result %v, err %v, want nil and %v", got, err, dbErr)
+	}
+	if got := cache.GetByIds(context.Background(), cache.NewLRU(0, 0), "product:", nil, fetcher, productId, time.Minute); got == nil || len(got) != 0 {
+		t.Errorf("got result %v, want empty map", got)
+	}
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+// LoaderFunc 回源加载单个值
+type LoaderFunc[T any] func(ctx cCtx) (T, error)
+
+// Get 读取单个对象缓存，未命中时调用 load 回源并写入缓存，加载流程与 GetIdMap 一致
+// 回源失败时返回零值和回源错误；仅缓存读写失败时返回回源得到的值和包装了 ErrBackend 的错误；
+// T 为指针、map 等引用类型时，返回值可能被并发调用方共享，不可修改
+func Get[T any](ctx cCtx, cacheIns Backend, key string, load LoaderFunc[T], ttl time.Duration, opts ...Option) (T, error) {
+	l := &loader[T]{
+		cacheIns: cacheIns,
+		key:      key,
+		fetch:    load,
+		empty:    func(v T) bool { return false },
+		duration: ttl,
+		opts:     newLoadOptions(opts),
+	}
+	return l.load(ctx)
+}
+
+// GetSlice 读取切片缓存，空切片仅在设置了 WithEmptyTTL 时缓存，错误语义与 Get 一致
+// 返回的切片为副本，元素为浅拷贝
+func GetSlice[T any](ctx cCtx, cacheIns Backend, key string, load LoaderFunc[[]T], ttl time.Duration, opts ...Option) ([]T, error) {
+	l := &loader[[]T]{
+		cacheIns: cacheIns,
+		key:      key,
+		fetch:    load,
+		empty:    func(v []T) bool { return len(v) == 0 },
+		duration: ttl,
+		opts:     newLoadOptions(opts),
+	}
+	v, err := l.load(ctx)
+	if err != nil && !errors.Is(err, ErrBackend) {
+		return nil, err
+	}
+	if v == nil {
+		return []T{}, err
+	}
+	return append(make([]T, 0, len(v)), v...), err
+}
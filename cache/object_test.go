@@ -0,0 +1,119 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/supernarsi/gotool/cache"
+)
+
+type profile struct {
+	Id       uint
+	Nickname string
+}
+
+func TestGet(t *testing.T) {
+	var calls int32
+	ctx := context.Background()
+	h := cache.NewLRU(0, 0)
+	keys := cache.NewKeyBuilder[profile]("user:profile", 1)
+	load := func(ctx context.Context) (profile, error) {
+		atomic.AddInt32(&calls, 1)
+		return profile{Id: 12, Nickname: "narsi"}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		got, err := keys.Get(ctx, h, []interface{}{12}, load, time.Minute)
+		if err != nil || got.Nickname != "narsi" {
+			t.Errorf("got result %v, err %v", got, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("loader called %d times, want 1", calls)
+	}
+	if _, ok, _ := h.Get(ctx, "user:profile:v1:12"); !ok {
+		t.Error("user:profile:v1:12 should be cached")
+	}
+
+	// 版本号变更后不再读取旧 key
+	keysV2 := cache.NewKeyBuilder[profile]("user:profile", 2)
+	if _, err := cache.Get(ctx, h, keysV2.Key(12), load, time.Minute); err != nil || calls != 2 {
+		t.Errorf("err %v, loader called %d times, want 2", err, calls)
+	}
+}
+
+func TestGetError(t *testing.T) {
+	loadErr := errors.New("not found")
+	load := func(ctx context.Context) (*profile, error) {
+		return nil, loadErr
+	}
+	got, err := cache.Get(context.Background(), cache.NewLRU(0, 0), "user:profile:err", load, time.Minute)
+	if got != nil || !errors.Is(err, loadErr) {
+		t.Errorf("got result %v, err %v, want nil and %v", got, err, loadErr)
+	}
+}
+
+func TestGetSlice(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []string
+		opts  []cache.Option
+		calls int32
+	}{
+		{"ranking", []string{"a", "b", "c"}, nil, 1},
+		{"empty not cached", nil, nil, 2},
+		{"empty cached", nil, []cache.Option{cache.WithEmptyTTL(time.Minute)}, 1},
+		{"with meta", []string{"a"}, []cache.Option{cache.WithStaleWhileRevalidate(time.Minute), cache.WithCodec(cache.MsgpackCodec)}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			h := cache.NewLRU(0, 0)
+			load := func(ctx context.Context) ([]string, error) {
+				atomic.AddInt32(&calls, 1)
+				return tt.items, nil
+			}
+			for i := 0; i < 2; i++ {
+				got, err := cache.GetSlice(context.Background(), h, "ranking", load, time.Minute, tt.opts...)
+				if err != nil || got == nil || len(got) != len(tt.items) {
+					t.Errorf("got result %v, err %v, want %v", got, err, tt.items)
+				}
+				if len(got) > 0 {
+					got[0] = "modified"
+				}
+			}
+			if calls != tt.calls {
+				t.Errorf("loader called %d times, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestKeyBuilder(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder cache.KeyBuilder[profile]
+		parts   []interface{}
+		want    string
+	}{
+		{"single part", cache.NewKeyBuilder[profile]("user", 1), []interface{}{12}, "user:v1:12"},
+		{"multi parts", cache.NewKeyBuilder[profile]("rank", 3), []interface{}{"daily", uint(20240501)}, "rank:v3:daily:20240501"},
+		{"no parts", cache.NewKeyBuilder[profile]("cfg", 0), nil, "cfg:v0:"},
+		{"escaped separator", cache.NewKeyBuilder[profile]("k", 1), []interface{}{"a:b", "c"}, "k:v1:a%3Ab:c"},
+		{"escaped separator", cache.NewKeyBuilder[profile]("k", 1), []interface{}{"a", "b:c"}, "k:v1:a:b%3Ac"},
+		{"escaped percent", cache.NewKeyBuilder[profile]("k", 1), []interface{}{"a%3Ab"}, "k:v1:a%253Ab"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.builder.Key(tt.parts...); got != tt.want {
+				t.Errorf("got result %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := cache.NewKeyBuilder[profile]("user", 2).Prefix(); got != "user:v2:" {
+		t.Errorf("got prefix %v, want user:v2:", got)
+	}
+}
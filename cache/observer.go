@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Observer 缓存加载过程的观测回调，实现需支持并发调用且不应阻塞
+type Observer interface {
+	OnHit(key string)
+	OnMiss(key string)
+	// OnFetch 一次回源完成（无论成功与否）及其耗时
+	OnFetch(key string, d time.Duration)
+	OnFetchError(key string, err error)
+	OnEncodeError(key string, err error)
+	OnDecodeError(key string, err error)
+	// OnWrite 成功写入缓存的字节数
+	OnWrite(key string, bytes int)
+}
+
+// NopObserver 空实现，可嵌入自定义 Observer 以只实现关心的回调
+type NopObserver struct{}
+
+func (NopObserver) OnHit(key string)                    {}
+func (NopObserver) OnMiss(key string)                   {}
+func (NopObserver) OnFetch(key string, d time.Duration) {}
+func (NopObserver) OnFetchError(key string, err error)  {}
+func (NopObserver) OnEncodeError(key string, err error) {}
+func (NopObserver) OnDecodeError(key string, err error) {}
+func (NopObserver) OnWrite(key string, bytes int)       {}
+
+// Stats 基于原子计数的 Observer
+type Stats struct {
+	hits         int64
+	misses       int64
+	fetches      int64
+	fetchNanos   int64
+	fetchErrors  int64
+	encodeErrors int64
+	decodeErrors int64
+	writes       int64
+	bytesWritten int64
+}
+
+// StatsSnapshot Stats 某一时刻的快照
+type StatsSnapshot struct {
+	Hits         int64   `json:"hits"`
+	Misses       int64   `json:"misses"`
+	HitRatio     float64 `json:"hit_ratio"`
+	Fetches      int64   `json:"fetches"`
+	FetchAvgMs   float64 `json:"fetch_avg_ms"`
+	FetchErrors  int64   `json:"fetch_errors"`
+	EncodeErrors int64   `json:"encode_errors"`
+	DecodeErrors int64   `json:"decode_errors"`
+	Writes       int64   `json:"writes"`
+	BytesWritten int64   `json:"bytes_written"`
+}
+
+func NewStats() *Stats {
+	return &Stats{}
+}
+
+func (s *Stats) OnHit(key string)  { atomic.AddInt64(&s.hits, 1) }
+func (s *Stats) OnMiss(key string) { atomic.AddInt64(&s.misses, 1) }
+
+func (s *Stats) OnFetch(key string, d time.Duration) {
+	atomic.AddInt64(&s.fetches, 1)
+	atomic.AddInt64(&s.fetchNanos, d.Nanoseconds())
+}
+
+func (s *Stats) OnFetchError(key string, err error)  { atomic.AddInt64(&s.fetchErrors, 1) }
+func (s *Stats) OnEncodeError(key string, err error) { atomic.AddInt64(&s.encodeErrors, 1) }
+func (s *Stats) OnDecodeError(key string, err error) { atomic.AddInt64(&s.decodeErrors, 1) }
+
+func (s *Stats) OnWrite(key string, bytes int) {
+	atomic.AddInt64(&s.writes, 1)
+	atomic.AddInt64(&s.bytesWritten, int64(bytes))
+}
+
+// Snapshot 返回当前计数
+func (s *Stats) Snapshot() StatsSnapshot {
+	snap := StatsSnapshot{
+		Hits:         atomic.LoadInt64(&s.hits),
+		Misses:       atomic.LoadInt64(&s.misses),
+		Fetches:      atomic.LoadInt64(&s.fetches),
+		FetchErrors:  atomic.LoadInt64(&s.fetchErrors),
+		EncodeErrors: atomic.LoadInt64(&s.encodeErrors),
+		DecodeErrors: atomic.LoadInt64(&s.decodeErrors),
+		Writes:       atomic.LoadInt64(&s.writes),
+		BytesWritten: atomic.LoadInt64(&s.bytesWritten),
+	}
+	if total := snap.Hits + snap.Misses; total > 0 {
+		snap.HitRatio = float64(snap.Hits) / float64(total)
+	}
+	if snap.Fetches > 0 {
+		snap.FetchAvgMs = float64(atomic.LoadInt64(&s.fetchNanos)) / float64(snap.Fetches) / float64(time.Millisecond)
+	}
+	return snap
+}
+
+// ExpvarObserver 将 Stats 快照发布到 expvar（/debug/vars）
+type ExpvarObserver struct {
+	*Stats
+}
+
+// expvarMu 使检查与发布成为原子操作，避免并发发布同名变量时 expvar.Publish panic
+var expvarMu sync.Mutex
+
+// NewExpvarObserver 以 name 发布缓存统计，name 已被占用时返回错误
+func NewExpvarObserver(name string) (*ExpvarObserver, error) {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if expvar.Get(name) != nil {
+		return nil, fmt.Errorf("cache: expvar %s already published", name)
+	}
+	o := &ExpvarObserver{Stats: NewStats()}
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return o.Snapshot()
+	}))
+	return o, nil
+}
@@ -0,0 +1,107 @@
+package cache_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/supernarsi/gotool/cache"
+)
+
+func TestStatsObserver(t *testing.T) {
+	var calls int32
+	ctx := context.Background()
+	h := cache.NewLRU(0, 0)
+	stats := cache.NewStats()
+	obs := cache.WithObserver(stats)
+
+	for i := 0; i < 4; i++ {
+		cache.GetIdMap(ctx, h, "product:obs", productFetcher(&calls, 0), productId, time.Minute, obs)
+	}
+	_ = h.Set(ctx, "product:obs:bad", []byte("not json"), 0)
+	cache.GetIdMap(ctx, h, "product:obs:bad", productFetcher(&calls, 0), productId, time.Minute, obs)
+	failing := func(ctx context.Context) ([]*product, error) {
+		return nil, errors.New("db down")
+	}
+	cache.GetIdMap(ctx, h, "product:obs:err", failing, productId, time.Minute, obs)
+
+	snap := stats.Snapshot()
+	want := cache.StatsSnapshot{
+		Hits:         3,
+		Misses:       3,
+		HitRatio:     0.5,
+		Fetches:      3,
+		FetchErrors:  1,
+		DecodeErrors: 1,
+		Writes:       2,
+	}
+	snap.FetchAvgMs, snap.BytesWritten = 0, 0
+	if snap != want {
+		t.Errorf("got snapshot %+v, want %+v", snap, want)
+	}
+	if stats.Snapshot().BytesWritten <= 0 {
+		t.Error("bytes written should be positive")
+	}
+}
+
+func TestStatsObserverGetByIds(t *testing.T) {
+	stats := cache.NewStats()
+	h := cache.NewLRU(0, 0)
+	r := &batchRecorder{db: map[uint]string{1: "apple", 2: "banana"}}
+	cache.GetByIds(context.Background(), h, "product:", []uint{1}, r.fetch, productId, time.Minute, cache.WithObserver(stats))
+	cache.GetByIds(context.Background(), h, "product:", []uint{1, 2}, r.fetch, productId, time.Minute, cache.WithObserver(stats))
+
+	snap := stats.Snapshot()
+	if snap.Hits != 1 || snap.Misses != 2 || snap.Fetches != 2 || snap.Writes != 2 {
+		t.Errorf("got snapshot %+v", snap)
+	}
+}
+
+var expvarSeq int32
+
+func TestExpvarObserver(t *testing.T) {
+	var calls int32
+	// expvar 无法注销，-count=N 重复运行时每次使用不同的名称
+	name := fmt.Sprintf("gotool_cache_test_%d", atomic.AddInt32(&expvarSeq, 1))
+
+	// 并发发布同名变量只有一个成功
+	var wg sync.WaitGroup
+	var created int32
+	results := make([]*cache.ExpvarObserver, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if o, err := cache.NewExpvarObserver(name); err == nil {
+				atomic.AddInt32(&created, 1)
+				results[i] = o
+			}
+		}(i)
+	}
+	wg.Wait()
+	if created != 1 {
+		t.Fatalf("got %d observers, want 1", created)
+	}
+	var obs *cache.ExpvarObserver
+	for _, o := range results {
+		if o != nil {
+			obs = o
+		}
+	}
+
+	cache.GetIdMap(context.Background(), cache.NewLRU(0, 0), "product:expvar", productFetcher(&calls, 0), productId, time.Minute, cache.WithObserver(obs))
+
+	var snap cache.StatsSnapshot
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &snap); err != nil {
+		t.Fatal(err)
+	}
+	if snap.Misses != 1 || snap.Fetches != 1 || snap.Writes != 1 {
+		t.Errorf("got snapshot %+v", snap)
+	}
+}
@@ -0,0 +1,108 @@
+package cache
+
+import "time"
+
+// Option 缓存加载选项
+type Option func(o *loadOptions)
+
+type loadOptions struct {
+	softTTL     time.Duration
+	beta        float64
+	emptyTTL    time.Duration
+	loadTimeout time.Duration
+	ser         serializer
+	tags        []string
+	obs         Observer
+}
+
+func newLoadOptions(opts []Option) loadOptions {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.obs == nil {
+		o.obs = NopObserver{}
+	}
+	o.ser.meta = o.withMeta()
+	return o
+}
+
+// withMeta 是否需要在缓存值旁额外存储刷新元数据
+func (o *loadOptions) withMeta() bool {
+	return o.softTTL > 0 || o.beta > 0
+}
+
+// WithStaleWhileRevalidate 设置软过期时间
+// 超过 softTTL 但未到缓存本身的过期时间（duration）时，直接返回旧值并在后台回源刷新；
+// softTTL 应小于 duration，否则不会生效；duration <= 0（永不过期）时按 softTTL 刷新
+func WithStaleWhileRevalidate(softTTL time.Duration) Option {
+	return func(o *loadOptions) {
+		o.softTTL = softTTL
+	}
+}
+
+// WithEarlyRefresh 开启概率提前刷新（XFetch）
+// 越接近过期、回源越慢，越可能提前触发后台刷新，避免多实例同时过期；beta 通常取 1，越大越积极
+// duration <= 0 且未设置软过期时间时没有过期时间点，不会提前刷新
+func WithEarlyRefresh(beta float64) Option {
+	return func(o *loadOptions) {
+		o.beta = beta
+	}
+}
+
+// WithEmptyTTL 缓存空结果（负缓存），避免空表每次都回源；ttl <= 0 表示不缓存空结果
+func WithEmptyTTL(ttl time.Duration) Option {
+	return func(o *loadOptions) {
+		o.emptyTTL = ttl
+	}
+}
+
+// WithLoadTimeout 限制单次回源的时长
+// 回源默认沿用发起回源的调用方 ctx 的截止时间；调用方 ctx 没有截止时间（如 context.Background()）时，
+// 建议设置该选项，避免回源卡住后同一 key 的后续未命中一直等待
+func WithLoadTimeout(d time.Duration) Option {
+	return func(o *loadOptions) {
+		o.loadTimeout = d
+	}
+}
+
+// WithCodec 指定缓存值的序列化方式，如 JSONCodec、GobCodec、MsgpackCodec
+// 设置任一编码选项（WithCodec、WithCompression、WithVersion）后，写入的值带有版本头部
+func WithCodec(c Codec) Option {
+	return func(o *loadOptions) {
+		o.ser.framed = true
+		o.ser.codec = c
+	}
+}
+
+// WithCompression 序列化后的数据不小于 minSize 字节时使用 c 压缩
+func WithCompression(c Compression, minSize int) Option {
+	return func(o *loadOptions) {
+		o.ser.framed = true
+		o.ser.compression = c
+		o.ser.minCompress = minSize
+	}
+}
+
+// WithVersion 设置缓存值的结构版本号
+// 读取到版本号不一致的值按未命中处理并重新回源，结构变更时递增即可平滑发布
+func WithVersion(v uint8) Option {
+	return func(o *loadOptions) {
+		o.ser.framed = true
+		o.ser.version = v
+	}
+}
+
+// WithTags 将缓存 key 注册到 tags 下，之后可通过 InvalidateTag 一并删除
+func WithTags(tags ...string) Option {
+	return func(o *loadOptions) {
+		o.tags = append(o.tags, tags...)
+	}
+}
+
+// WithObserver 设置观测回调，用于统计命中率、回源耗时、写入字节数等
+func WithObserver(obs Observer) Option {
+	return func(o *loadOptions) {
+		o.obs = obs
+	}
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// loadGroup 合并同一后端、同一缓存 key、同一值类型上的并发回源请求
+var loadGroup = &flightGroup{}
+
+// flightKey 合并回源的粒度；不同后端或不同值类型即使 key 相同也各自回源
+type flightKey struct {
+	backend interface{}
+	typ     reflect.Type
+	key     string
+}
+
+// newFlightKey 后端类型不可比较时无法判断是否为同一实例，使用唯一值使其不参与合并
+func newFlightKey[V any](cacheIns Backend, key string) flightKey {
+	var backend interface{} = cacheIns
+	if t := reflect.TypeOf(cacheIns); t != nil && !t.Comparable() {
+		backend = new(byte)
+	}
+	return flightKey{backend: backend, typ: reflect.TypeOf((*V)(nil)).Elem(), key: key}
+}
+
+type flightCall struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+}
+
+// flightGroup 简易 singleflight：同一 key 同时只有一个 fn 在执行，其余调用方等待并共享其结果
+type flightGroup struct {
+	mu sync.Mutex
+	m  map[flightKey]*flightCall
+}
+
+// Do 执行并合并同一 flightKey 的调用
+// fn 在独立协程中运行，使用剥离了取消信号但保留截止时间的 ctx，因此某个调用方取消不会中断其他调用方共享的回源；
+// timeout > 0 时截止时间不晚于 timeout 之后。到达截止时间时即使 fn 未返回也会释放该调用，等待方收到超时错误；
+// 每个调用方仍各自响应自己 ctx 的取消，取消时立即返回 ctx.Err()
+func (g *flightGroup) Do(ctx cCtx, key flightKey, timeout time.Duration, fn func(ctx cCtx) (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	c, ok := g.m[key]
+	if !ok {
+		c = g.start(ctx, key, timeout, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Go 在后台触发 fn，不等待结果；同一 flightKey 已有执行中的调用时直接返回
+func (g *flightGroup) Go(ctx cCtx, key flightKey, timeout time.Duration, fn func(ctx cCtx) (interface{}, error)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.m[key]; !ok {
+		g.start(ctx, key, timeout, fn)
+	}
+}
+
+// start 登记并启动一次调用，调用方需持有 g.mu
+func (g *flightGroup) start(ctx cCtx, key flightKey, timeout time.Duration, fn func(ctx cCtx) (interface{}, error)) *flightCall {
+	if g.m == nil {
+		g.m = make(map[flightKey]*flightCall)
+	}
+	c := &flightCall{done: make(chan struct{})}
+	g.m[key] = c
+	go g.doCall(ctx, key, c, timeout, fn)
+	return c
+}
+
+func (g *flightGroup) doCall(parent cCtx, key flightKey, c *flightCall, timeout time.Duration, fn func(ctx cCtx) (interface{}, error)) {
+	ctx, cancel := loadContext(parent, timeout)
+	defer cancel()
+
+	var val interface{}
+	var err error
+	result := make(chan struct{})
+	go func() {
+		defer close(result)
+		defer func() {
+			if r := recover(); r != nil {
+				val, err = nil, fmt.Errorf("cache: load %s panic: %v", key.key, r)
+			}
+		}()
+		val, err = fn(ctx)
+	}()
+
+	select {
+	case <-result:
+		c.val, c.err = val, err
+	case <-ctx.Done():
+		// fn 未响应 ctx 时仍在后台运行，其结果被丢弃
+		c.err = fmt.Errorf("cache: load %s: %w", key.key, ctx.Err())
+	}
+	g.mu.Lock()
+	delete(g.m, key)
+	g.mu.Unlock()
+	close(c.done)
+}
+
+// loadContext 回源使用的 ctx：不继承 parent 的取消，保留其截止时间，并按 timeout 进一步收紧
+func loadContext(parent cCtx, timeout time.Duration) (cCtx, context.CancelFunc) {
+	ctx := cCtx(detachedCtx{parent: parent})
+	deadline, ok := parent.Deadline()
+	if timeout > 0 {
+		if d := time.Now().Add(timeout); !ok || d.Before(deadline) {
+			deadline, ok = d, true
+		}
+	}
+	if !ok {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
+// detachedCtx 保留父 ctx 的值，但不继承其取消与超时
+type detachedCtx struct {
+	parent context.Context
+}
+
+func (detachedCtx) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedCtx) Done() <-chan struct{}       { return nil }
+func (detachedCtx) Err() error                  { return nil }
+func (c detachedCtx) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
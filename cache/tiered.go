@@ -0,0 +1,242 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+const defaultInvalidateChannel = "gotool:cache:invalidate"
+
+// PubSub 多实例间广播本地缓存失效消息的通道
+type PubSub interface {
+	Publish(ctx context.Context, channel string, msg []byte) error
+	// Subscribe 订阅 channel，返回取消订阅函数
+	Subscribe(ctx context.Context, channel string, handler func(msg []byte)) (unsubscribe func(), err error)
+}
+
+// TieredConfig 两级缓存配置
+type TieredConfig struct {
+	L1      Backend       // 进程内缓存，nil 时使用 NewLRU(10000, 0)
+	L2      Backend       // 远端缓存，必填
+	L1TTL   time.Duration // L1 过期时间，<= 0 时为 1 分钟
+	Bus     PubSub        // 失效广播通道，nil 时仅失效本实例的 L1
+	Channel string        // 广播 channel，默认 gotool:cache:invalidate
+}
+
+// Tiered 两级缓存后端：短 TTL 的进程内 L1 + 远端 L2
+// 通过 Tiered 写入或删除的 key 会广播给其他实例，使其 L1 失效
+//
+// L1 与其他 Backend 一样保存编码后的字节，命中 L1 只省去远端往返：GetIdMap、GetKeyMap 每次调用仍会解码，
+// 并为调用方复制一份新的 map。读取频率极高且不修改结果的场景，可在调用方按 L1TTL 自行保存解码后的 map
+type Tiered struct {
+	l1          Backend
+	l2          Backend
+	l1TTL       time.Duration
+	bus         PubSub
+	channel     string
+	id          string
+	unsubscribe func()
+}
+
+type invalidateMsg struct {
+	Src  string   `json:"src"`
+	Keys []string `json:"keys"`
+}
+
+// NewTiered 创建两级缓存，配置了 Bus 时订阅失效广播，不再使用时应调用 Close
+func NewTiered(cfg TieredConfig) (*Tiered, error) {
+	if cfg.L2 == nil {
+		return nil, errors.New("cache: tiered L2 backend is required")
+	}
+	if cfg.L1 == nil {
+		cfg.L1 = NewLRU(10000, 0)
+	}
+	if cfg.L1TTL <= 0 {
+		cfg.L1TTL = time.Minute
+	}
+	if cfg.Channel == "" {
+		cfg.Channel = defaultInvalidateChannel
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	t := &Tiered{
+		l1:      cfg.L1,
+		l2:      cfg.L2,
+		l1TTL:   cfg.L1TTL,
+		bus:     cfg.Bus,
+		channel: cfg.Channel,
+		id:      hex.EncodeToString(id),
+	}
+
+	if t.bus != nil {
+		unsubscribe, err := t.bus.Subscribe(context.Background(), t.channel, t.onInvalidate)
+		if err != nil {
+			return nil, err
+		}
+		t.unsubscribe = unsubscribe
+	}
+	return t, nil
+}
+
+// Get 优先读 L1，未命中时读 L2 并回填 L1
+func (t *Tiered) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if val, ok, err := t.l1.Get(ctx, key); err == nil && ok {
+		return val, true, nil
+	}
+	val, ok, err := t.l2.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	_ = t.l1.Set(ctx, key, val, t.l1TTL)
+	return val, true, nil
+}
+
+// GetMulti 批量读取，L1 未命中的 key 再从 L2 读取
+func (t *Tiered) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	values, _ := getMulti(ctx, t.l1, keys)
+	if values == nil {
+		values = make(map[string][]byte, len(keys))
+	}
+	missing := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if _, ok := values[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	remote, err := getMulti(ctx, t.l2, missing)
+	for key, val := range remote {
+		values[key] = val
+		_ = t.l1.Set(ctx, key, val, t.l1TTL)
+	}
+	return values, err
+}
+
+// Set 写入 L2 和本地 L1，并广播使其他实例的 L1 失效
+func (t *Tiered) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := t.l2.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	l1TTL := t.l1TTL
+	if ttl > 0 && ttl < l1TTL {
+		l1TTL = ttl
+	}
+	_ = t.l1.Set(ctx, key, value, l1TTL)
+	return t.publish(ctx, key)
+}
+
+// Delete 删除 L2 和 L1 中的 key，并广播使其他实例的 L1 失效
+func (t *Tiered) Delete(ctx context.Context, keys ...string) error {
+	if err := t.l2.Delete(ctx, keys...); err != nil {
+		return err
+	}
+	return t.Invalidate(ctx, keys...)
+}
+
+// Invalidate 仅使所有实例的 L1 失效，用于 L2 已被其他途径更新的场景
+func (t *Tiered) Invalidate(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_ = t.l1.Delete(ctx, keys...)
+	return t.publish(ctx, keys...)
+}
+
+// AddTag tag 关联保存在 L2，所有实例共享
+func (t *Tiered) AddTag(ctx context.Context, tag string, keys ...string) error {
+	return addTag(ctx, t.l2, tag, 0, keys...)
+}
+
+// PopTag 从 L2 取出并清空 tag 下的 key
+func (t *Tiered) PopTag(ctx context.Context, tag string) ([]string, error) {
+	return popTag(ctx, t.l2, tag)
+}
+
+// tagBackend 加载时直接在 L2 上注册 tag，L2 未实现 TagStore 时可按 key 的过期时间清理 tag 列表
+func (t *Tiered) tagBackend() Backend {
+	return t.l2
+}
+
+// Close 取消订阅失效广播
+func (t *Tiered) Close() error {
+	if t.unsubscribe != nil {
+		t.unsubscribe()
+		t.unsubscribe = nil
+	}
+	return nil
+}
+
+func (t *Tiered) publish(ctx context.Context, keys ...string) error {
+	if t.bus == nil {
+		return nil
+	}
+	msg, err := json.Marshal(invalidateMsg{Src: t.id, Keys: keys})
+	if err != nil {
+		return err
+	}
+	return t.bus.Publish(ctx, t.channel, msg)
+}
+
+func (t *Tiered) onInvalidate(msg []byte) {
+	var m invalidateMsg
+	if err := json.Unmarshal(msg, &m); err != nil || m.Src == t.id {
+		return
+	}
+	_ = t.l1.Delete(context.Background(), m.Keys...)
+}
+
+// MemPubSub 进程内 PubSub，消息同步投递给订阅者，主要用于测试
+type MemPubSub struct {
+	mu     sync.RWMutex
+	nextId int
+	subs   map[string]map[int]func(msg []byte)
+}
+
+// NewMemPubSub 创建进程内 PubSub
+func NewMemPubSub() *MemPubSub {
+	return &MemPubSub{subs: make(map[string]map[int]func(msg []byte))}
+}
+
+// Publish 将消息同步投递给 channel 的所有订阅者
+func (p *MemPubSub) Publish(ctx context.Context, channel string, msg []byte) error {
+	p.mu.RLock()
+	handlers := make([]func(msg []byte), 0, len(p.subs[channel]))
+	for _, h := range p.subs[channel] {
+		handlers = append(handlers, h)
+	}
+	p.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(msg)
+	}
+	return nil
+}
+
+// Subscribe 订阅 channel
+func (p *MemPubSub) Subscribe(ctx context.Context, channel string, handler func(msg []byte)) (func(), error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.subs[channel] == nil {
+		p.subs[channel] = make(map[int]func(msg []byte))
+	}
+	id := p.nextId
+	p.nextId++
+	p.subs[channel][id] = handler
+
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		delete(p.subs[channel], id)
+	}, nil
+}
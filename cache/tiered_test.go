@@ -0,0 +1,131 @@
+package cache_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/supernarsi/gotool/cache"
+)
+
+// countBackend 记录读取次数的后端
+type countBackend struct {
+	*cache.LRU
+	gets int32
+}
+
+func (c *countBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	atomic.AddInt32(&c.gets, 1)
+	return c.LRU.Get(ctx, key)
+}
+
+func (c *countBackend) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	atomic.AddInt32(&c.gets, 1)
+	return c.LRU.GetMulti(ctx, keys)
+}
+
+func newTiered(t *testing.T, l2 cache.Backend, bus cache.PubSub) *cache.Tiered {
+	t.Helper()
+	tc, err := cache.NewTiered(cache.TieredConfig{L2: l2, L1TTL: time.Minute, Bus: bus})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = tc.Close() })
+	return tc
+}
+
+func TestTieredGet(t *testing.T) {
+	var calls int32
+	ctx := context.Background()
+	l2 := &countBackend{LRU: cache.NewLRU(0, 0)}
+	tc := newTiered(t, l2, nil)
+
+	for i := 0; i < 5; i++ {
+		if got := cache.GetKeyMap(ctx, tc, "product:tiered", productFetcher(&calls, 0), productName, time.Hour); len(got) != 2 {
+			t.Errorf("got result %v", got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("fetcher called %d times, want 1", calls)
+	}
+	if got := atomic.LoadInt32(&l2.gets); got > 2 {
+		t.Errorf("L2 read %d times, want at most 2", got)
+	}
+}
+
+func TestTieredInvalidation(t *testing.T) {
+	ctx := context.Background()
+	l2 := cache.NewLRU(0, 0)
+	bus := cache.NewMemPubSub()
+	a := newTiered(t, l2, bus)
+	b := newTiered(t, l2, bus)
+
+	_ = a.Set(ctx, "k", []byte("v1"), time.Hour)
+	if got, _, _ := b.Get(ctx, "k"); string(got) != "v1" {
+		t.Errorf("got result %s, want v1", got)
+	}
+
+	_ = a.Set(ctx, "k", []byte("v2"), time.Hour)
+	if got, _, _ := b.Get(ctx, "k"); string(got) != "v2" {
+		t.Errorf("got result %s, want v2 after broadcast", got)
+	}
+
+	_ = a.Delete(ctx, "k")
+	if _, ok, _ := b.Get(ctx, "k"); ok {
+		t.Error("k should be deleted on all instances")
+	}
+
+	// L2 被其他途径更新后，通过 Invalidate 失效所有实例的 L1
+	_ = l2.Set(ctx, "k", []byte("v3"), time.Hour)
+	_, _, _ = b.Get(ctx, "k")
+	_ = l2.Set(ctx, "k", []byte("v4"), time.Hour)
+	if got, _, _ := b.Get(ctx, "k"); string(got) != "v3" {
+		t.Errorf("got result %s, want L1 value v3", got)
+	}
+	_ = a.Invalidate(ctx, "k")
+	if got, _, _ := b.Get(ctx, "k"); string(got) != "v4" {
+		t.Errorf("got result %s, want v4 after invalidate", got)
+	}
+}
+
+func TestTieredClose(t *testing.T) {
+	ctx := context.Background()
+	l2 := cache.NewLRU(0, 0)
+	bus := cache.NewMemPubSub()
+	a := newTiered(t, l2, bus)
+	b := newTiered(t, l2, bus)
+
+	_ = a.Set(ctx, "k", []byte("v1"), time.Hour)
+	_, _, _ = b.Get(ctx, "k")
+	_ = b.Close()
+	_ = a.Set(ctx, "k", []byte("v2"), time.Hour)
+	if got, _, _ := b.Get(ctx, "k"); string(got) != "v1" {
+		t.Errorf("got result %s, want stale L1 value v1 after close", got)
+	}
+}
+
+func TestTieredGetByIds(t *testing.T) {
+	ctx := context.Background()
+	l2 := &countBackend{LRU: cache.NewLRU(0, 0)}
+	tc := newTiered(t, l2, nil)
+	r := &batchRecorder{db: map[uint]string{1: "apple", 2: "banana"}}
+
+	for i := 0; i < 3; i++ {
+		if got := cache.GetByIds(ctx, tc, "product:", []uint{1, 2}, r.fetch, productId, time.Hour); len(got) != 2 {
+			t.Errorf("got result %v", got)
+		}
+	}
+	if len(r.calls) != 1 {
+		t.Errorf("got fetch calls %v, want 1 call", r.calls)
+	}
+	if got := atomic.LoadInt32(&l2.gets); got != 1 {
+		t.Errorf("L2 read %d times, want 1", got)
+	}
+}
+
+func TestNewTieredRequiresL2(t *testing.T) {
+	if _, err := cache.NewTiered(cache.TieredConfig{}); err == nil {
+		t.Error("want error without L2")
+	}
+}
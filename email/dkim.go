@@ -0,0 +1,389 @@
+package email
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrDKIMNoSignature = errors.New("email: dkim signature not found")
+	ErrDKIMBodyHash    = errors.New("email: dkim body hash mismatch")
+	ErrDKIMSignature   = errors.New("email: dkim signature verification failed")
+)
+
+// defaultDKIMHeaders 默认签名的头部，邮件中不存在的头部不参与签名
+var defaultDKIMHeaders = []string{
+	"From", "Reply-To", "Subject", "Date", "To", "Cc", "Message-ID",
+	"MIME-Version", "Content-Type", "Content-Transfer-Encoding",
+	"List-Unsubscribe", "List-Unsubscribe-Post",
+}
+
+// DKIMConfig DKIM 签名配置，头部与正文均使用 relaxed 规范化
+type DKIMConfig struct {
+	Domain     string        // 签名域 d=
+	Selector   string        // 选择器 s=，公钥发布在 <Selector>._domainkey.<Domain> 的 TXT 记录
+	PrivateKey crypto.Signer // *rsa.PrivateKey（rsa-sha256）或 ed25519.PrivateKey（ed25519-sha256）
+	Headers    []string      // 签名的头部，为空时使用默认列表；From 总会被签名
+	Expiration time.Duration // 大于 0 时设置签名过期时间 x=
+}
+
+func (c *DKIMConfig) algorithm() (string, error) {
+	switch c.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		return "rsa-sha256", nil
+	case ed25519.PrivateKey:
+		return "ed25519-sha256", nil
+	default:
+		return "", fmt.Errorf("email: unsupported dkim key type %T", c.PrivateKey)
+	}
+}
+
+// DKIMSign 对完整的邮件内容签名，返回在头部最前面加上 DKIM-Signature 的邮件
+func DKIMSign(data []byte, cfg *DKIMConfig) ([]byte, error) {
+	if cfg.Domain == "" || cfg.Selector == "" || cfg.PrivateKey == nil {
+		return nil, errors.New("email: dkim domain, selector and private key are required")
+	}
+	algo, err := cfg.algorithm()
+	if err != nil {
+		return nil, err
+	}
+	data = toCRLF(data)
+	header, body := splitMessage(data)
+	fields := parseHeaderFields(header)
+
+	names := cfg.Headers
+	if len(names) == 0 {
+		names = defaultDKIMHeaders
+	}
+	if !containsFold(names, "From") {
+		names = append([]string{"From"}, names...)
+	}
+	var signed []string
+	for _, name := range names {
+		// 同名头部出现多次时逐个签名，与验证时自下而上的选取顺序一致
+		for i := countFields(fields, name); i > 0; i-- {
+			signed = append(signed, strings.ToLower(name))
+		}
+	}
+
+	bodyHash := sha256.Sum256(relaxedBody(body))
+	now := time.Now()
+	tags := []string{
+		"v=1",
+		"a=" + algo,
+		"c=relaxed/relaxed",
+		"d=" + cfg.Domain,
+		"s=" + cfg.Selector,
+		"t=" + strconv.FormatInt(now.Unix(), 10),
+	}
+	if cfg.Expiration > 0 {
+		tags = append(tags, "x="+strconv.FormatInt(now.Add(cfg.Expiration).Unix(), 10))
+	}
+	tags = append(tags,
+		"h="+strings.Join(signed, ":"),
+		"bh="+base64.StdEncoding.EncodeToString(bodyHash[:]),
+		"b=",
+	)
+	sigField := "DKIM-Signature: " + strings.Join(tags, "; ")
+
+	digest := headerHash(fields, signed, sigField)
+	opts := crypto.Hash(0)
+	if algo == "rsa-sha256" {
+		opts = crypto.SHA256
+	}
+	sig, err := cfg.PrivateKey.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(sigField)
+	buf.WriteString(foldBase64(base64.StdEncoding.EncodeToString(sig)))
+	buf.WriteString("\r\n")
+	buf.Write(data)
+	return buf.Bytes(), nil
+}
+
+// DKIMKeyLookup 按签名域与选择器查找公钥
+type DKIMKeyLookup func(domain string, selector string) (crypto.PublicKey, error)
+
+// DKIMVerify 验证邮件中第一个 DKIM-Signature，lookup 为 nil 时通过 DNS TXT 记录查找公钥
+func DKIMVerify(data []byte, lookup DKIMKeyLookup) error {
+	if lookup == nil {
+		lookup = lookupDKIMKeyDNS
+	}
+	data = toCRLF(data)
+	header, body := splitMessage(data)
+	fields := parseHeaderFields(header)
+
+	var sigField string
+	for _, f := range fields {
+		if strings.EqualFold(fieldName(f), "DKIM-Signature") {
+			sigField = f
+			break
+		}
+	}
+	if sigField == "" {
+		return ErrDKIMNoSignature
+	}
+	tags := parseDKIMTags(sigField[strings.IndexByte(sigField, ':')+1:])
+	if tags["v"] != "1" || tags["c"] != "relaxed/relaxed" {
+		return fmt.Errorf("email: unsupported dkim signature v=%s c=%s", tags["v"], tags["c"])
+	}
+	if x := tags["x"]; x != "" {
+		if exp, err := strconv.ParseInt(x, 10, 64); err != nil || time.Now().Unix() > exp {
+			return fmt.Errorf("%w: signature expired", ErrDKIMSignature)
+		}
+	}
+
+	bodyHash := sha256.Sum256(relaxedBody(body))
+	if base64.StdEncoding.EncodeToString(bodyHash[:]) != tags["bh"] {
+		return ErrDKIMBodyHash
+	}
+	sig, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDKIMSignature, err)
+	}
+
+	pub, err := lookup(tags["d"], tags["s"])
+	if err != nil {
+		return err
+	}
+	var signed []string
+	for _, h := range strings.Split(tags["h"], ":") {
+		signed = append(signed, strings.TrimSpace(h))
+	}
+	digest := headerHash(fields, signed, stripSignature(sigField))
+
+	switch tags["a"] {
+	case "rsa-sha256":
+		key, ok := pub.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, sig) != nil {
+			return ErrDKIMSignature
+		}
+	case "ed25519-sha256":
+		key, ok := pub.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(key, digest, sig) {
+			return ErrDKIMSignature
+		}
+	default:
+		return fmt.Errorf("email: unsupported dkim algorithm %s", tags["a"])
+	}
+	return nil
+}
+
+// DKIMRecord 生成发布在 <selector>._domainkey.<domain> 的 TXT 记录
+func DKIMRecord(pub crypto.PublicKey) (string, error) {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			return "", err
+		}
+		return "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(der), nil
+	case ed25519.PublicKey:
+		return "v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(key), nil
+	default:
+		return "", fmt.Errorf("email: unsupported dkim key type %T", pub)
+	}
+}
+
+func lookupDKIMKeyDNS(domain string, selector string) (crypto.PublicKey, error) {
+	records, err := net.LookupTXT(selector + "._domainkey." + domain)
+	if err != nil {
+		return nil, err
+	}
+	tags := parseDKIMTags(strings.Join(records, ""))
+	p, err := base64.StdEncoding.DecodeString(tags["p"])
+	if err != nil || len(p) == 0 {
+		return nil, fmt.Errorf("email: invalid dkim record for %s._domainkey.%s", selector, domain)
+	}
+	if tags["k"] == "ed25519" {
+		if len(p) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("email: invalid ed25519 dkim key for %s._domainkey.%s", selector, domain)
+		}
+		return ed25519.PublicKey(p), nil
+	}
+	return x509.ParsePKIXPublicKey(p)
+}
+
+// headerHash 按 h= 的顺序自下而上选取头部，relaxed 规范化后与去掉 b= 值的签名头一起计算哈希
+func headerHash(fields []string, signed []string, sigField string) []byte {
+	h := sha256.New()
+	used := make(map[int]bool)
+	for _, name := range signed {
+		for i := len(fields) - 1; i >= 0; i-- {
+			if !used[i] && strings.EqualFold(fieldName(fields[i]), name) {
+				used[i] = true
+				h.Write([]byte(relaxedHeader(fields[i])))
+				h.Write([]byte("\r\n"))
+				break
+			}
+		}
+	}
+	h.Write([]byte(relaxedHeader(sigField)))
+	return h.Sum(nil)
+}
+
+// relaxedHeader RFC 6376 3.4.2：名称小写，展开折行，连续空白合并为一个空格，去掉冒号两侧与末尾空白
+func relaxedHeader(field string) string {
+	i := strings.IndexByte(field, ':')
+	name := strings.ToLower(strings.TrimSpace(field[:i]))
+	value := strings.Join(strings.Fields(field[i+1:]), " ")
+	return name + ":" + value
+}
+
+// relaxedBody RFC 6376 3.4.4：去掉行尾空白，行内连续空白合并为一个空格，去掉末尾空行
+func relaxedBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	var buf bytes.Buffer
+	blank := 0
+	for _, line := range lines {
+		line = strings.TrimRight(collapseWSP(line), " ")
+		if line == "" {
+			blank++
+			continue
+		}
+		for ; blank > 0; blank-- {
+			buf.WriteString("\r\n")
+		}
+		buf.WriteString(line)
+		buf.WriteString("\r\n")
+	}
+	return buf.Bytes()
+}
+
+func collapseWSP(s string) string {
+	if !strings.ContainsAny(s, " \t") {
+		return s
+	}
+	var b strings.Builder
+	space := false
+	for i := 0; i < len(s); i++ {
+		if s[i] == ' ' || s[i] == '\t' {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteByte(s[i])
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// toCRLF 将单独的 LF 转为 CRLF，与 SMTP DATA 传输时的转换一致
+func toCRLF(data []byte) []byte {
+	if !bytes.Contains(data, []byte("\n")) {
+		return data
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+	for i, c := range data {
+		if c == '\n' && (i == 0 || data[i-1] != '\r') {
+			buf.WriteByte('\r')
+		}
+		buf.WriteByte(c)
+	}
+	return buf.Bytes()
+}
+
+// splitMessage 拆分头部与正文，头部不含分隔的空行
+func splitMessage(data []byte) (header []byte, body []byte) {
+	if i := bytes.Index(data, []byte("\r\n\r\n")); i >= 0 {
+		return data[:i+2], data[i+4:]
+	}
+	return data, nil
+}
+
+// parseHeaderFields 将头部拆分为字段，折行保留在字段内，不含末尾的 CRLF
+func parseHeaderFields(header []byte) []string {
+	var fields []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(header), "\r\n"), "\r\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(fields) > 0 {
+			fields[len(fields)-1] += "\r\n" + line
+			continue
+		}
+		if strings.IndexByte(line, ':') > 0 {
+			fields = append(fields, line)
+		}
+	}
+	return fields
+}
+
+func fieldName(field string) string {
+	return strings.TrimSpace(field[:strings.IndexByte(field, ':')])
+}
+
+func countFields(fields []string, name string) int {
+	n := 0
+	for _, f := range fields {
+		if strings.EqualFold(fieldName(f), name) {
+			n++
+		}
+	}
+	return n
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDKIMTags 解析 tag=value 列表，值中的空白被去掉
+func parseDKIMTags(s string) map[string]string {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(s, ";") {
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		tags[strings.TrimSpace(k)] = strings.Join(strings.Fields(v), "")
+	}
+	return tags
+}
+
+// stripSignature 去掉签名头中 b= 的值，保留其余内容
+func stripSignature(field string) string {
+	i := strings.IndexByte(field, ':') + 1
+	parts := strings.Split(field[i:], ";")
+	for j, part := range parts {
+		if k, _, ok := strings.Cut(part, "="); ok && strings.TrimSpace(k) == "b" {
+			parts[j] = part[:strings.IndexByte(part, '=')+1]
+		}
+	}
+	return field[:i] + strings.Join(parts, ";")
+}
+
+// foldBase64 签名值按 72 字符折行，避免头部过长
+func foldBase64(s string) string {
+	const lineLen = 72
+	var b strings.Builder
+	for len(s) > lineLen {
+		b.WriteString(s[:lineLen])
+		b.WriteString("\r\n\t")
+		s = s[lineLen:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
@@ -0,0 +1,172 @@
+package email_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/supernarsi/gotool/email"
+)
+
+func staticKey(pub crypto.PublicKey) email.DKIMKeyLookup {
+	return func(domain string, selector string) (crypto.PublicKey, error) {
+		if domain != "example.com" || selector != "mail" {
+			return nil, errors.New("unknown selector")
+		}
+		return pub, nil
+	}
+}
+
+func signedMessage(t *testing.T, cfg *email.DKIMConfig) []byte {
+	t.Helper()
+	msg := &email.Message{
+		From:    "noreply@example.com",
+		To:      []string{"to@example.com"},
+		Subject: "订单通知",
+		Text:    "hello  world \nsecond line",
+		HTML:    "<p>hello</p>",
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := email.DKIMSign(data, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestDKIMSignVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		key  crypto.Signer
+		pub  crypto.PublicKey
+		algo string
+	}{
+		{"rsa", rsaKey, &rsaKey.PublicKey, "a=rsa-sha256"},
+		{"ed25519", edKey, edPub, "a=ed25519-sha256"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed := signedMessage(t, &email.DKIMConfig{Domain: "example.com", Selector: "mail", PrivateKey: tt.key})
+			if !bytes.HasPrefix(signed, []byte("DKIM-Signature: v=1; "+tt.algo+"; c=relaxed/relaxed; d=example.com; s=mail;")) {
+				t.Errorf("unexpected signature header:\n%s", signed)
+			}
+			if err := email.DKIMVerify(signed, staticKey(tt.pub)); err != nil {
+				t.Fatal(err)
+			}
+
+			// relaxed 规范化容忍空白与折行的变化
+			relaxed := bytes.Replace(signed, []byte("\r\nMIME-Version: 1.0"), []byte("\r\nMIME-Version:\r\n  1.0 "), 1)
+			relaxed = append(relaxed, "\r\n\r\n"...)
+			if err := email.DKIMVerify(relaxed, staticKey(tt.pub)); err != nil {
+				t.Errorf("relaxed canonicalization: %v", err)
+			}
+
+			tampered := bytes.Replace(signed, []byte("Subject: "), []byte("Subject: Re: "), 1)
+			if err := email.DKIMVerify(tampered, staticKey(tt.pub)); !errors.Is(err, email.ErrDKIMSignature) {
+				t.Errorf("got err %v, want %v", err, email.ErrDKIMSignature)
+			}
+			tampered = bytes.Replace(signed, []byte("hello"), []byte("hallo"), 1)
+			if err := email.DKIMVerify(tampered, staticKey(tt.pub)); !errors.Is(err, email.ErrDKIMBodyHash) {
+				t.Errorf("got err %v, want %v", err, email.ErrDKIMBodyHash)
+			}
+		})
+	}
+}
+
+func TestDKIMHeaders(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	signed := signedMessage(t, &email.DKIMConfig{
+		Domain:     "example.com",
+		Selector:   "mail",
+		PrivateKey: key,
+		Headers:    []string{"Subject", "Date", "X-Not-Present"},
+	})
+	header := string(signed[:bytes.Index(signed, []byte("\r\n\r\n"))])
+	if !strings.Contains(header, "h=from:subject:date;") {
+		t.Errorf("got header %v, want h=from:subject:date", header)
+	}
+
+	if err := email.DKIMVerify([]byte("Subject: x\r\n\r\nbody"), nil); !errors.Is(err, email.ErrDKIMNoSignature) {
+		t.Errorf("got err %v, want %v", err, email.ErrDKIMNoSignature)
+	}
+	if _, err := email.DKIMSign(signed, &email.DKIMConfig{Domain: "example.com", PrivateKey: key}); err == nil {
+		t.Error("selector should be required")
+	}
+}
+
+func TestDKIMRecord(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	record, err := email.DKIMRecord(pub)
+	if err != nil || !strings.HasPrefix(record, "v=DKIM1; k=ed25519; p=") {
+		t.Errorf("got record %v, err %v", record, err)
+	}
+}
+
+func TestSendMailDKIM(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	srv := newFakeServer(t, nil)
+	cfg := srv.config()
+	cfg.DKIM = &email.DKIMConfig{Domain: "example.com", Selector: "mail", PrivateKey: key}
+
+	tests := []struct {
+		name   string
+		mailer email.Mail
+	}{
+		{"std", email.NewMailStd(cfg)},
+		{"gomail", email.NewMailGoMail(cfg)},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.mailer.SendMail(context.Background(), []string{"to@example.com"}, "subject", "<p>body</p>"); err != nil {
+				t.Fatal(err)
+			}
+			received := srv.received()[i]
+			if err := email.DKIMVerify([]byte(received), staticKey(pub)); err != nil {
+				t.Errorf("received message should verify: %v\n%s", err, received)
+			}
+		})
+	}
+}
+
+// RFC 8463 附录 A 的示例签名
+func TestDKIMVerifyRFC8463(t *testing.T) {
+	msg := "DKIM-Signature: v=1; a=ed25519-sha256; c=relaxed/relaxed;\r\n" +
+		" d=football.example.com; i=@football.example.com;\r\n" +
+		" q=dns/txt; s=brisbane; t=1528637909; h=from : to :\r\n" +
+		" subject : date : message-id : from : subject : date;\r\n" +
+		" bh=2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8=;\r\n" +
+		" b=/gCrinpcQOoIfuHNQIbq4pgh9kyIK3AQUdt9OdqQehSwhEIug4D11Bus\r\n" +
+		" Fa3bT3FY5OsU7ZbnKELq+eXdp1Q1Dw==\r\n" +
+		"From: Joe SixPack <joe@football.example.com>\r\n" +
+		"To: Suzie Q <suzie@shopping.example.net>\r\n" +
+		"Subject: Is dinner ready?\r\n" +
+		"Date: Fri, 11 Jul 2003 21:00:37 -0700 (PDT)\r\n" +
+		"Message-ID: <20030712040037.46341.5F8J@football.example.com>\r\n" +
+		"\r\n" +
+		"Hi.\r\n\r\nWe lost the game.  Are you hungry yet?\r\n\r\nJoe.\r\n"
+	pub, _ := base64.StdEncoding.DecodeString("11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo=")
+	lookup := func(domain string, selector string) (crypto.PublicKey, error) {
+		return ed25519.PublicKey(pub), nil
+	}
+	if err := email.DKIMVerify([]byte(msg), lookup); err != nil {
+		t.Error(err)
+	}
+}
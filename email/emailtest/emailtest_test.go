@@ -0,0 +1,179 @@
+package emailtest_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/supernarsi/gotool/email"
+	"github.com/supernarsi/gotool/email/emailtest"
+)
+
+func TestRecorder(t *testing.T) {
+	r := emailtest.NewRecorder()
+	ctx := context.Background()
+	if err := r.SendMail(ctx, []string{"a@example.com"}, "hello", "<p>hi</p>"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Send(ctx, &email.Message{To: []string{"B <b@example.com>"}, Bcc: []string{"c@example.com"}, Subject: "second"}); err != nil {
+		t.Fatal(err)
+	}
+
+	r.AssertCount(t, 2)
+	if got := r.AssertSentTo(t, "A@example.com").Subject; got != "hello" {
+		t.Errorf("got result %v, want %v", got, "hello")
+	}
+	r.AssertSentTo(t, "c@example.com")
+	r.AssertNotSentTo(t, "d@example.com")
+	if got := r.Last().Subject; got != "second" {
+		t.Errorf("got result %v, want %v", got, "second")
+	}
+
+	errDown := errors.New("down")
+	r.FailWith(errDown)
+	if err := r.SendMail(ctx, []string{"a@example.com"}, "x", "x"); !errors.Is(err, errDown) {
+		t.Errorf("got err %v, want %v", err, errDown)
+	}
+	r.AssertCount(t, 2)
+	r.Reset()
+	r.AssertCount(t, 0)
+}
+
+func TestServer(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  emailtest.ServerConfig
+	}{
+		{"plain", emailtest.ServerConfig{TLSMode: email.TLSNone}},
+		{"starttls", emailtest.ServerConfig{TLSMode: email.TLSStartTLS, Username: "user", Password: "secret"}},
+		{"implicit tls", emailtest.ServerConfig{TLSMode: email.TLSImplicit, Username: "user", Password: "secret"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := emailtest.StartServer(t, tt.cfg)
+			msg := &email.Message{
+				To:          []string{"张三 <zhang@example.com>"},
+				Bcc:         []string{"hidden@example.com"},
+				Subject:     "您好",
+				Text:        "纯文本",
+				HTML:        "<p>HTML</p>",
+				Attachments: []email.Attachment{{Filename: "报告.csv", ContentType: "text/csv", Data: []byte("a,b\n1,2\n")}},
+			}
+			mailers := []email.Mail{email.NewMailStd(srv.MailerConfig()), email.NewMailGoMail(srv.MailerConfig())}
+			for _, m := range mailers {
+				if err := m.Send(context.Background(), msg); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			received := srv.Messages()
+			if len(received) != len(mailers) {
+				t.Fatalf("got %d messages, want %d", len(received), len(mailers))
+			}
+			for _, r := range received {
+				if r.From != "noreply@example.com" || len(r.To) != 2 || r.To[1] != "hidden@example.com" {
+					t.Errorf("got envelope %v %v", r.From, r.To)
+				}
+				if r.Subject != msg.Subject || r.Text != msg.Text || r.HTML != msg.HTML {
+					t.Errorf("got subject %q text %q html %q", r.Subject, r.Text, r.HTML)
+				}
+				if len(r.Attachments) != 1 || r.Attachments[0].Filename != "报告.csv" || string(r.Attachments[0].Data) != "a,b\n1,2\n" {
+					t.Errorf("got attachments %+v", r.Attachments)
+				}
+				if r.Header.Get("Bcc") != "" {
+					t.Error("Bcc should not be in headers")
+				}
+			}
+		})
+	}
+}
+
+func TestServerRejects(t *testing.T) {
+	srv := emailtest.StartServer(t, emailtest.ServerConfig{
+		TLSMode:  email.TLSStartTLS,
+		Username: "user",
+		Password: "secret",
+		Reject:   map[string]int{"gone@example.com": 550},
+	})
+
+	cfg := srv.MailerConfig()
+	err := email.NewMailStd(cfg).SendMail(context.Background(), []string{"gone@example.com"}, "s", "b")
+	if !errors.Is(err, email.ErrRecipientRejected) {
+		t.Errorf("got err %v, want %v", err, email.ErrRecipientRejected)
+	}
+
+	cfg.Password = "wrong"
+	err = email.NewMailStd(cfg).SendMail(context.Background(), []string{"to@example.com"}, "s", "b")
+	if !errors.Is(err, email.ErrAuth) {
+		t.Errorf("got err %v, want %v", err, email.ErrAuth)
+	}
+
+	// 未信任自签名证书时 TLS 握手失败
+	cfg = srv.MailerConfig()
+	cfg.TLSConfig = nil
+	err = email.NewMailStd(cfg).SendMail(context.Background(), []string{"to@example.com"}, "s", "b")
+	if !errors.Is(err, email.ErrConnection) {
+		t.Errorf("got err %v, want %v", err, email.ErrConnection)
+	}
+	if n := len(srv.Messages()); n != 0 {
+		t.Errorf("got %d messages, want 0", n)
+	}
+}
+
+func TestServerCloseIdleConn(t *testing.T) {
+	srv, err := emailtest.NewServer(emailtest.ServerConfig{TLSMode: email.TLSNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("tcp", srv.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	tc := textproto.NewConn(conn)
+	if _, _, err = tc.ReadResponse(220); err != nil {
+		t.Fatal(err)
+	}
+
+	// 客户端保持空闲连接时 Close 不等待读超时
+	done := make(chan error, 1)
+	go func() { done <- srv.Close() }()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Errorf("got err %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close should not wait for idle connections")
+	}
+	if _, err = tc.ReadLine(); err == nil {
+		t.Error("connection should be closed by server")
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	msg := &email.Message{
+		From:        "a@example.com",
+		To:          []string{"b@example.com"},
+		Subject:     "inline",
+		HTML:        `<img src="cid:logo">`,
+		Attachments: []email.Attachment{{Filename: "logo.png", ContentID: "logo", Data: []byte{0x89, 'P', 'N', 'G'}}},
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := emailtest.ParseMessage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.HTML != msg.HTML || r.Text != "" {
+		t.Errorf("got html %q text %q", r.HTML, r.Text)
+	}
+	if len(r.Attachments) != 1 || r.Attachments[0].ContentID != "logo" || r.Attachments[0].ContentType != "image/png" {
+		t.Errorf("got attachments %+v", r.Attachments)
+	}
+}
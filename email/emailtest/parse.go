@@ -0,0 +1,104 @@
+package emailtest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+
+	"github.com/supernarsi/gotool/email"
+)
+
+// Received 接收并解析后的邮件
+type Received struct {
+	From string   // 信封发件人（MAIL FROM）
+	To   []string // 信封收件人（RCPT TO），包含 Bcc
+
+	Raw         []byte      // 原始内容，换行为 LF
+	Header      mail.Header // 头部，值未解码
+	Subject     string      // 已按 RFC 2047 解码
+	Text        string      // 第一个 text/plain 部分
+	HTML        string      // 第一个 text/html 部分
+	Attachments []email.Attachment
+}
+
+// ParseMessage 解析 MIME 邮件，解码传输编码并拆分正文与附件
+func ParseMessage(data []byte) (*Received, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	r := &Received{Raw: data, Header: msg.Header}
+	dec := new(mime.WordDecoder)
+	if r.Subject, err = dec.DecodeHeader(msg.Header.Get("Subject")); err != nil {
+		return nil, err
+	}
+	if err = r.walk(textproto.MIMEHeader(msg.Header), msg.Body); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// walk 递归处理 multipart，叶子部分按 Content-Disposition 与 Content-ID 归为正文或附件
+func (r *Received) walk(header textproto.MIMEHeader, body io.Reader) error {
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err = r.walk(p.Header, p); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	// 无 Content-Disposition 与 Content-ID 的文本部分视为正文
+	contentID := header.Get("Content-ID")
+	dispType, dispParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	if dispType == "" && contentID == "" && strings.HasPrefix(mediaType, "text/") {
+		switch {
+		case mediaType == "text/plain" && r.Text == "":
+			r.Text = string(data)
+			return nil
+		case mediaType == "text/html" && r.HTML == "":
+			r.HTML = string(data)
+			return nil
+		}
+	}
+	r.Attachments = append(r.Attachments, email.Attachment{
+		Filename:    dispParams["filename"],
+		ContentType: mediaType,
+		ContentID:   strings.Trim(contentID, "<>"),
+		Data:        data,
+	})
+	return nil
+}
@@ -0,0 +1,115 @@
+package emailtest
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/supernarsi/gotool/email"
+)
+
+// Recorder 记录发送的邮件而不实际发送的 email.Mail 实现，可并发使用
+type Recorder struct {
+	mu   sync.Mutex
+	msgs []*email.Message
+	err  error
+}
+
+// NewRecorder 创建空的 Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) SendMail(ctx context.Context, address []string, subject string, body string) error {
+	return r.Send(ctx, &email.Message{To: address, Subject: subject, HTML: body})
+}
+
+// Send 记录邮件的副本；设置了 FailWith 时返回该错误且不记录
+func (r *Recorder) Send(ctx context.Context, msg *email.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	cp := *msg
+	r.msgs = append(r.msgs, &cp)
+	return nil
+}
+
+// FailWith 之后的发送都返回 err，传入 nil 恢复正常
+func (r *Recorder) FailWith(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+// Messages 已记录的邮件，按发送顺序
+func (r *Recorder) Messages() []*email.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*email.Message(nil), r.msgs...)
+}
+
+// Last 最后一封邮件，没有时返回 nil
+func (r *Recorder) Last() *email.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.msgs) == 0 {
+		return nil
+	}
+	return r.msgs[len(r.msgs)-1]
+}
+
+// Reset 清空已记录的邮件
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = nil
+}
+
+// SentTo 收件人（To、Cc、Bcc）中包含 address 的邮件，忽略大小写与显示名
+func (r *Recorder) SentTo(address string) []*email.Message {
+	var res []*email.Message
+	for _, m := range r.Messages() {
+		rcpts, _ := m.Recipients()
+		for _, rcpt := range rcpts {
+			if strings.EqualFold(rcpt, address) {
+				res = append(res, m)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// AssertCount 断言已记录 n 封邮件
+func (r *Recorder) AssertCount(tb testing.TB, n int) {
+	tb.Helper()
+	if got := len(r.Messages()); got != n {
+		tb.Errorf("emailtest: got %d messages, want %d", got, n)
+	}
+}
+
+// AssertSentTo 断言有发给 address 的邮件并返回最后一封
+func (r *Recorder) AssertSentTo(tb testing.TB, address string) *email.Message {
+	tb.Helper()
+	msgs := r.SentTo(address)
+	if len(msgs) == 0 {
+		tb.Fatalf("emailtest: no message sent to %s", address)
+	}
+	return msgs[len(msgs)-1]
+}
+
+// AssertNotSentTo 断言没有发给 address 的邮件
+func (r *Recorder) AssertNotSentTo(tb testing.TB, address string) {
+	tb.Helper()
+	if n := len(r.SentTo(address)); n > 0 {
+		tb.Errorf("emailtest: got %d messages sent to %s, want none", n, address)
+	}
+}
+
+var _ email.Mail = (*Recorder)(nil)
@@ -0,0 +1,358 @@
+package emailtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/supernarsi/gotool/email"
+)
+
+// ServerConfig 本地 SMTP 服务配置
+type ServerConfig struct {
+	TLSMode  email.TLSMode // 默认 TLSImplicit，与 MailerConfig 一致；测试明文连接时使用 TLSNone
+	Username string        // 非空时要求 AUTH PLAIN
+	Password string
+	Reject   map[string]int // 被拒绝的收件人及 SMTP 响应码，如 550
+}
+
+// Server 仅供测试使用的本地 SMTP 服务，接收邮件并解析后保存在内存中
+// 使用自动生成的自签名证书支持 STARTTLS 与隐式 TLS，MailerConfig 返回信任该证书的发信配置
+type Server struct {
+	cfg      ServerConfig
+	ln       net.Listener
+	tls      *tls.Config
+	certPool *x509.CertPool
+
+	mu       sync.Mutex
+	received []*Received
+	conns    map[net.Conn]struct{} // 正在处理的连接，Close 时主动关闭
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewServer 在 127.0.0.1 的随机端口启动服务，使用完毕后需调用 Close
+func NewServer(cfg ServerConfig) (*Server, error) {
+	cert, pool, err := selfSignedCert()
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		cfg:      cfg,
+		tls:      &tls.Config{Certificates: []tls.Certificate{cert}},
+		certPool: pool,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	if cfg.TLSMode == email.TLSImplicit {
+		s.ln, err = tls.Listen("tcp", "127.0.0.1:0", s.tls)
+	} else {
+		s.ln, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// StartServer 启动服务并在测试结束时关闭
+func StartServer(tb testing.TB, cfg ServerConfig) *Server {
+	tb.Helper()
+	s, err := NewServer(cfg)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+// Addr 监听地址
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// MailerConfig 指向本服务的发信配置，TLS 模式与认证信息与服务端一致
+func (s *Server) MailerConfig() email.MailerConfig {
+	return email.MailerConfig{
+		Host:        "127.0.0.1",
+		Port:        s.ln.Addr().(*net.TCPAddr).Port,
+		Username:    s.cfg.Username,
+		Password:    s.cfg.Password,
+		FromAddress: "noreply@example.com",
+		TLSMode:     s.cfg.TLSMode,
+		TLSConfig:   &tls.Config{RootCAs: s.certPool, ServerName: "127.0.0.1"},
+		DialTimeout: 5 * time.Second,
+		SendTimeout: 10 * time.Second,
+	}
+}
+
+// Messages 已接收的邮件，按接收顺序
+func (s *Server) Messages() []*Received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Received(nil), s.received...)
+}
+
+// Reset 清空已接收的邮件
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.received = nil
+}
+
+// Close 停止监听，关闭客户端仍保持的连接并等待处理结束
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		if !s.track(conn) {
+			_ = conn.Close()
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(conn)
+			s.serve(conn)
+		}()
+	}
+}
+
+// track 记录新连接，服务已关闭时返回 false
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
+
+// session 一个 SMTP 连接的状态
+type session struct {
+	tc      *textproto.Conn
+	secure  bool
+	authed  bool
+	from    string
+	rcpts   []string
+	hasFrom bool
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(time.Minute))
+	_, secure := conn.(*tls.Conn)
+	ss := &session{tc: textproto.NewConn(conn), secure: secure}
+	ss.reply(220, "emailtest ESMTP")
+	for {
+		line, err := ss.tc.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			ext := []string{"emailtest", "8BITMIME"}
+			if s.cfg.TLSMode == email.TLSStartTLS && !ss.secure {
+				ext = append(ext, "STARTTLS")
+			}
+			if s.cfg.Username != "" {
+				ext = append(ext, "AUTH PLAIN")
+			}
+			ss.replyLines(250, ext)
+		case "STARTTLS":
+			if s.cfg.TLSMode != email.TLSStartTLS || ss.secure {
+				ss.reply(502, "5.5.1 STARTTLS not available")
+				continue
+			}
+			ss.reply(220, "2.0.0 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			ss = &session{tc: textproto.NewConn(tlsConn), secure: true}
+		case "AUTH":
+			ss.authed = s.auth(ss, arg)
+		case "MAIL":
+			if !s.ready(ss) {
+				continue
+			}
+			ss.from, ss.rcpts, ss.hasFrom = pathArg(arg), nil, true
+			ss.reply(250, "2.1.0 OK")
+		case "RCPT":
+			if !ss.hasFrom {
+				ss.reply(503, "5.5.1 need MAIL first")
+				continue
+			}
+			addr := pathArg(arg)
+			if code, ok := s.cfg.Reject[addr]; ok {
+				ss.reply(code, "recipient rejected")
+				continue
+			}
+			ss.rcpts = append(ss.rcpts, addr)
+			ss.reply(250, "2.1.5 OK")
+		case "DATA":
+			if len(ss.rcpts) == 0 {
+				ss.reply(503, "5.5.1 need RCPT first")
+				continue
+			}
+			ss.reply(354, "end data with <CR><LF>.<CR><LF>")
+			data, err := ss.tc.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			r, err := ParseMessage(data)
+			if err != nil {
+				ss.reply(554, "5.6.0 "+err.Error())
+				continue
+			}
+			r.From, r.To = ss.from, ss.rcpts
+			s.mu.Lock()
+			s.received = append(s.received, r)
+			s.mu.Unlock()
+			ss.from, ss.rcpts, ss.hasFrom = "", nil, false
+			ss.reply(250, "2.0.0 queued")
+		case "RSET":
+			ss.from, ss.rcpts, ss.hasFrom = "", nil, false
+			ss.reply(250, "2.0.0 OK")
+		case "NOOP":
+			ss.reply(250, "2.0.0 OK")
+		case "QUIT":
+			ss.reply(221, "2.0.0 bye")
+			return
+		default:
+			ss.reply(502, "5.5.2 command not recognized")
+		}
+	}
+}
+
+// ready 检查 MAIL 前是否已满足 TLS 与认证要求
+func (s *Server) ready(ss *session) bool {
+	if s.cfg.TLSMode == email.TLSStartTLS && !ss.secure {
+		ss.reply(530, "5.7.0 must issue STARTTLS first")
+		return false
+	}
+	if s.cfg.Username != "" && !ss.authed {
+		ss.reply(530, "5.7.0 authentication required")
+		return false
+	}
+	return true
+}
+
+func (s *Server) auth(ss *session, arg string) bool {
+	mech, resp, _ := strings.Cut(arg, " ")
+	if !strings.EqualFold(mech, "PLAIN") {
+		ss.reply(504, "5.5.4 unrecognized authentication type")
+		return false
+	}
+	if resp == "" {
+		ss.reply(334, "")
+		line, err := ss.tc.ReadLine()
+		if err != nil {
+			return false
+		}
+		resp = line
+	}
+	b, err := base64.StdEncoding.DecodeString(resp)
+	parts := strings.Split(string(b), "\x00")
+	if err != nil || len(parts) != 3 || parts[1] != s.cfg.Username || parts[2] != s.cfg.Password {
+		ss.reply(535, "5.7.8 authentication credentials invalid")
+		return false
+	}
+	ss.reply(235, "2.7.0 authentication successful")
+	return true
+}
+
+func (ss *session) reply(code int, msg string) {
+	_ = ss.tc.PrintfLine("%d %s", code, msg)
+}
+
+// replyLines 多行响应，除最后一行外状态码后为 "-"
+func (ss *session) replyLines(code int, lines []string) {
+	for i, l := range lines {
+		sep := "-"
+		if i == len(lines)-1 {
+			sep = " "
+		}
+		_ = ss.tc.PrintfLine("%d%s%s", code, sep, l)
+	}
+}
+
+// pathArg 从 "FROM:<a@b> SIZE=10" 中取出地址
+func pathArg(arg string) string {
+	_, path, _ := strings.Cut(arg, ":")
+	path = strings.TrimSpace(path)
+	if i := strings.IndexByte(path, '>'); i >= 0 {
+		path = path[:i]
+	}
+	return strings.TrimPrefix(path, "<")
+}
+
+// selfSignedCert 生成 127.0.0.1、::1 与 localhost 的自签名证书及信任它的证书池
+func selfSignedCert() (tls.Certificate, *x509.CertPool, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "emailtest"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		DNSNames:              []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool, nil
+}
@@ -0,0 +1,111 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+var (
+	ErrConnection        = errors.New("email: connection failed")
+	ErrAuth              = errors.New("email: authentication failed")
+	ErrRecipientRejected = errors.New("email: recipient rejected")
+)
+
+// SendError SMTP 会话中某一步失败，可用 errors.Is 判断 ErrConnection、ErrAuth 或 context 错误
+// Op 为 dial 或非 SMTP 响应导致的错误归为 ErrConnection，Op 为 auth 归为 ErrAuth
+type SendError struct {
+	Op   string // 失败的步骤：dial、auth、mail、data、send 等
+	Code int    // SMTP 响应码，非 SMTP 响应导致的错误为 0
+	Err  error
+}
+
+func (e *SendError) Error() string {
+	return "email: " + e.Op + ": " + e.Err.Error()
+}
+
+func (e *SendError) Unwrap() []error {
+	if kind := e.kind(); kind != nil {
+		return []error{kind, e.Err}
+	}
+	return []error{e.Err}
+}
+
+// Temporary 是否可稍后重试：4xx 响应或网络错误
+func (e *SendError) Temporary() bool {
+	if e.Code != 0 {
+		return e.Code >= 400 && e.Code < 500
+	}
+	return e.kind() == ErrConnection
+}
+
+// kind 错误类别，ctx 结束导致的错误不归类
+func (e *SendError) kind() error {
+	if errors.Is(e.Err, context.Canceled) || errors.Is(e.Err, context.DeadlineExceeded) {
+		return nil
+	}
+	switch {
+	case e.Op == "auth":
+		return ErrAuth
+	case e.Op == "dial" || e.Code == 0:
+		return ErrConnection
+	}
+	return nil
+}
+
+// RejectedRecipient 被服务端拒绝的收件人
+type RejectedRecipient struct {
+	Address string
+	Code    int // SMTP 响应码，如 550
+	Message string
+}
+
+// RecipientError 有收件人被拒绝，此时邮件不会发送给任何人
+type RecipientError struct {
+	Rejected []RejectedRecipient
+}
+
+func (e *RecipientError) Error() string {
+	list := make([]string, len(e.Rejected))
+	for i, r := range e.Rejected {
+		list[i] = fmt.Sprintf("%s (%d %s)", r.Address, r.Code, r.Message)
+	}
+	return ErrRecipientRejected.Error() + ": " + strings.Join(list, ", ")
+}
+
+func (e *RecipientError) Is(target error) bool {
+	return target == ErrRecipientRejected
+}
+
+// Temporary 是否所有被拒绝的收件人都是 4xx 响应
+func (e *RecipientError) Temporary() bool {
+	for _, r := range e.Rejected {
+		if r.Code < 400 || r.Code >= 500 {
+			return false
+		}
+	}
+	return len(e.Rejected) > 0
+}
+
+// sendErr 将 SMTP 会话中的错误包装为 SendError
+// ctx 已结束时以 ctx.Err() 为准，因为此时的网络错误只是截止时间被提前触发的结果
+func sendErr(ctx context.Context, op string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return &SendError{Op: op, Err: ctxErr}
+	}
+	// 连接截止时间取自 ctx 时，读写超时可能先于 ctx 自身的计时器触发
+	var netErr net.Error
+	if d, ok := ctx.Deadline(); ok && !time.Now().Before(d) && errors.As(err, &netErr) && netErr.Timeout() {
+		return &SendError{Op: op, Err: context.DeadlineExceeded}
+	}
+	e := &SendError{Op: op, Err: err}
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) {
+		e.Code = tpErr.Code
+	}
+	return e
+}
@@ -0,0 +1,139 @@
+package email
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// HTMLToText 将 HTML 正文转换为纯文本，用作 multipart/alternative 中的 text/plain 部分
+// 段落与标题之间空一行，列表项以 "- " 开头，链接地址附在文字后的括号中，script、style 等内容被忽略
+func HTMLToText(s string) string {
+	var (
+		buf   strings.Builder
+		skip  int // 位于 head、script、style 内的层数
+		pre   int
+		hrefs []string
+		space bool // 上一段文字以空白结尾
+	)
+	newline := func(n int) {
+		text := buf.String()
+		trailing := len(text) - len(strings.TrimRight(text, "\n"))
+		if text == "" {
+			return
+		}
+		for ; trailing < n; trailing++ {
+			buf.WriteByte('\n')
+		}
+	}
+
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		tok := z.Token()
+		switch tt {
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			text := tok.Data
+			if pre == 0 {
+				lead := text != "" && isSpace(text[0])
+				trail := text != "" && isSpace(text[len(text)-1])
+				text = strings.Join(strings.Fields(text), " ")
+				if text == "" {
+					space = space || lead
+					continue
+				}
+				// 行内元素两侧的空白合并为一个空格
+				if (space || lead) && buf.Len() > 0 && !isSpace(buf.String()[buf.Len()-1]) {
+					buf.WriteByte(' ')
+				}
+				space = trail
+			}
+			buf.WriteString(text)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			switch tok.Data {
+			case "head", "script", "style", "title":
+				if tt == html.StartTagToken {
+					skip++
+				}
+			case "br":
+				buf.WriteByte('\n')
+			case "p", "h1", "h2", "h3", "h4", "h5", "h6", "table", "blockquote", "ul", "ol":
+				newline(2)
+			case "div", "tr", "section", "header", "footer":
+				newline(1)
+			case "pre":
+				newline(2)
+				pre++
+			case "li":
+				newline(1)
+				buf.WriteString("- ")
+			case "td", "th":
+				if !strings.HasSuffix(buf.String(), "\n") && buf.Len() > 0 {
+					buf.WriteByte('\t')
+				}
+			case "hr":
+				newline(1)
+				buf.WriteString("----")
+				newline(1)
+			case "img":
+				if alt := attr(tok, "alt"); alt != "" && skip == 0 {
+					buf.WriteString(alt)
+				}
+			case "a":
+				hrefs = append(hrefs, attr(tok, "href"))
+			}
+		case html.EndTagToken:
+			switch tok.Data {
+			case "head", "script", "style", "title":
+				if skip > 0 {
+					skip--
+				}
+			case "p", "h1", "h2", "h3", "h4", "h5", "h6", "table", "blockquote", "ul", "ol":
+				newline(2)
+			case "div", "tr", "section", "header", "footer", "li":
+				newline(1)
+			case "pre":
+				if pre > 0 {
+					pre--
+				}
+				newline(2)
+			case "a":
+				if n := len(hrefs); n > 0 {
+					href := hrefs[n-1]
+					hrefs = hrefs[:n-1]
+					if href != "" && !strings.HasPrefix(href, "#") && !strings.HasSuffix(buf.String(), href) {
+						buf.WriteString(" (" + strings.TrimPrefix(href, "mailto:") + ")")
+					}
+				}
+			}
+		}
+	}
+
+	lines := strings.Split(buf.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\t' || c == '\r'
+}
+
+func attr(tok html.Token, key string) string {
+	for _, a := range tok.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
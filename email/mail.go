@@ -2,25 +2,122 @@ package email
 
 import (
 	"context"
+	"crypto/tls"
+	"net"
 	"net/mail"
+	"strconv"
+	"time"
 )
 
 const (
-	mailHost = ""
-	mailPort = 465
-	mailPass = ""
+	defaultDialTimeout = 10 * time.Second
+	defaultSendTimeout = time.Minute
 )
 
 type Mail interface {
-	SendMail(ctx context.Context, address []string, subject string, body string) bool
+	// SendMail 以 HTML 正文发送给 address
+	SendMail(ctx context.Context, address []string, subject string, body string) error
+	// Send 发送完整的邮件
+	Send(ctx context.Context, msg *Message) error
 }
 
-var defSender = ""
+// TLSMode SMTP 连接的加密方式
+type TLSMode int
 
+const (
+	TLSImplicit TLSMode = iota // 建立连接即 TLS，通常为 465 端口
+	TLSStartTLS                // 明文连接后通过 STARTTLS 升级，通常为 587 端口；MailGoMail 不强制升级
+	TLSNone                    // 不加密，仅用于内网中继或本地测试
+)
+
+// MailerConfig SMTP 发信配置
+type MailerConfig struct {
+	Host        string
+	Port        int           // 为 0 时按 TLSMode 取 465 / 587 / 25
+	Username    string        // 为空时使用 FromAddress
+	Password    string        // 为空时不进行 AUTH
+	FromName    string        // 发件人名称
+	FromAddress string        // 发件人地址
+	TLSMode     TLSMode       // 加密方式，默认 TLSImplicit
+	TLSConfig   *tls.Config   // 为 nil 时使用以 Host 校验证书的默认配置
+	DialTimeout time.Duration // 建立连接（含 TLS 握手）超时，默认 10 秒
+	SendTimeout time.Duration // 单次发送会话超时，默认 1 分钟
+	DKIM        *DKIMConfig   // 非 nil 时发送前对邮件进行 DKIM 签名
+}
+
+func (c MailerConfig) withDefaults() MailerConfig {
+	if c.Port == 0 {
+		switch c.TLSMode {
+		case TLSStartTLS:
+			c.Port = 587
+		case TLSNone:
+			c.Port = 25
+		default:
+			c.Port = 465
+		}
+	}
+	if c.Username == "" {
+		c.Username = c.FromAddress
+	}
+	if c.DialTimeout <= 0 {
+		c.DialTimeout = defaultDialTimeout
+	}
+	if c.SendTimeout <= 0 {
+		c.SendTimeout = defaultSendTimeout
+	}
+	return c
+}
+
+func (c MailerConfig) addr() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// from 格式化后的 From 头，名称按 RFC 2047 编码
+func (c MailerConfig) from() string {
+	return (&mail.Address{Name: c.FromName, Address: c.FromAddress}).String()
+}
+
+// prepare 生成信封与待发送的邮件内容，配置了 DKIM 时完成签名
+func (c MailerConfig) prepare(msg *Message) (from string, rcpts []string, data []byte, err error) {
+	if from, rcpts, err = msg.envelope(c.from()); err != nil {
+		return "", nil, nil, err
+	}
+	if data, err = msg.render(c.from(), time.Now()); err != nil {
+		return "", nil, nil, err
+	}
+	if c.DKIM != nil {
+		if data, err = DKIMSign(data, c.DKIM); err != nil {
+			return "", nil, nil, err
+		}
+	}
+	return from, rcpts, data, nil
+}
+
+func (c MailerConfig) tlsConfig() *tls.Config {
+	if c.TLSConfig != nil {
+		return c.TLSConfig
+	}
+	return &tls.Config{ServerName: c.Host}
+}
+
+// NewMailStd 基于 net/smtp 的发信实例，不同配置的实例可在同一进程中共存
+func NewMailStd(cfg MailerConfig) *MailStd {
+	return &MailStd{cfg: cfg.withDefaults()}
+}
+
+// NewMailGoMail 基于 gomail 的发信实例，不同配置的实例可在同一进程中共存
+func NewMailGoMail(cfg MailerConfig) *MailGoMail {
+	return &MailGoMail{cfg: cfg.withDefaults()}
+}
+
+// InitMailGoMail 创建未配置的 gomail 发信实例
+//
+// Deprecated: 使用 NewMailGoMail
 func InitMailGoMail() *MailGoMail {
-	return &MailGoMail{}
+	return NewMailGoMail(MailerConfig{})
 }
 
+// IsEmailValid 宽松校验，接受显示名与不含点的域名；严格校验使用 Validator
 func IsEmailValid(email string) bool {
 	_, err := mail.ParseAddress(email)
 	return err == nil
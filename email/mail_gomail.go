@@ -1,40 +1,65 @@
 package email
 
 import (
+	"bytes"
 	"context"
-	"crypto/tls"
+	"errors"
+	"net/textproto"
 
 	"gopkg.in/gomail.v2"
 )
 
-type MailGoMail struct{}
-
-func (m *MailGoMail) SendMail(ctx context.Context, address []string, subject string, body string) bool {
-	gm := gomail.NewMessage()
-
-	// Set E-Mail defSender
-	gm.SetHeader("From", defSender)
-
-	// Set E-Mail receivers
-	gm.SetHeader("To", address...)
-
-	// Set E-Mail subject
-	gm.SetHeader("Subject", subject)
-
-	// Set E-Mail body. You can set plain text or html with text/html
-	gm.SetBody("text/html", body)
+// MailGoMail 基于 gomail 的发信实现，邮件内容与 MailStd 一致，仅使用 gomail 的 SMTP 连接
+// gomail 的建连超时固定为 10 秒；TLSNone 模式下服务端支持 STARTTLS 时 gomail 仍会升级连接
+// TLSStartTLS 模式下仅在服务端声明 STARTTLS 时升级，否则以明文继续发送（含 AUTH），不会返回错误，
+// 无法防止中间人去掉 STARTTLS 声明的降级攻击；需要强制 STARTTLS 时使用 MailStd 或 MailPool
+// gomail 不支持 context，ctx 仅在建连前与投递前检查；无法区分被拒绝的收件人，RCPT 错误以 SendError 返回
+type MailGoMail struct {
+	cfg MailerConfig
+}
 
-	// Settings for SMTP server
-	d := gomail.NewDialer(mailHost, mailPort, defSender, mailPass)
+func (m *MailGoMail) SendMail(ctx context.Context, address []string, subject string, body string) error {
+	return m.Send(ctx, &Message{To: address, Subject: subject, HTML: body})
+}
 
-	// This is only needed when SSL/TLS certificate is not valid on server.
-	// In production this should be set to false.
-	d.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+// Send 发送完整的邮件
+func (m *MailGoMail) Send(ctx context.Context, msg *Message) error {
+	from, rcpts, data, err := m.cfg.prepare(msg)
+	if err != nil {
+		return err
+	}
 
-	// Now send E-Mail
-	if err := d.DialAndSend(gm); err != nil {
-		return false
+	if err = ctx.Err(); err != nil {
+		return &SendError{Op: "dial", Err: err}
+	}
+	s, err := m.dialer().Dial()
+	if err != nil {
+		// gomail 在 Dial 中完成 AUTH，只能按响应码区分认证失败
+		var tpErr *textproto.Error
+		if errors.As(err, &tpErr) && tpErr.Code >= 530 && tpErr.Code <= 538 {
+			return sendErr(ctx, "auth", err)
+		}
+		return sendErr(ctx, "dial", err)
+	}
+	defer s.Close()
+	if err = ctx.Err(); err != nil {
+		return &SendError{Op: "send", Err: err}
 	}
+	if err = s.Send(from, rcpts, bytes.NewReader(data)); err != nil {
+		return sendErr(ctx, "send", err)
+	}
+	return nil
+}
 
-	return true
+func (m *MailGoMail) dialer() *gomail.Dialer {
+	username := m.cfg.Username
+	if m.cfg.Password == "" {
+		// gomail 在 Username 非空时才进行 AUTH
+		username = ""
+	}
+	d := gomail.NewDialer(m.cfg.Host, m.cfg.Port, username, m.cfg.Password)
+	d.SSL = m.cfg.TLSMode == TLSImplicit
+	// 非 SSL 时 gomail 总是尝试 STARTTLS，但服务端未声明时不会报错
+	d.TLSConfig = m.cfg.tlsConfig()
+	return d
 }
@@ -3,91 +3,169 @@ package email
 import (
 	"context"
 	"crypto/tls"
-	"fmt"
+	"errors"
 	"net"
 	"net/smtp"
+	"net/textproto"
+	"time"
 )
 
-type MailStd struct{}
+// MailStd 基于 net/smtp 的发信实现
+type MailStd struct {
+	cfg MailerConfig
+}
 
-func (m *MailStd) SendMail(ctx context.Context, address []string, subject string, body string) bool {
-	host := mailHost
-	port := mailPort
-	password := mailPass
+func (m *MailStd) SendMail(ctx context.Context, address []string, subject string, body string) error {
+	return m.Send(ctx, &Message{To: address, Subject: subject, HTML: body})
+}
 
-	header := make(map[string]string)
-	header["From"] = defSender
-	header["Subject"] = subject
-	header["Content-Type"] = "text/html; charset=UTF-8"
+// Send 发送完整的邮件，ctx 的截止时间与取消同时作用于建连和发送
+func (m *MailStd) Send(ctx context.Context, msg *Message) error {
+	from, rcpts, data, err := m.cfg.prepare(msg)
+	if err != nil {
+		return err
+	}
+	return sendMailWithConfig(ctx, m.cfg, from, rcpts, data)
+}
 
-	message := ""
-	for k, v := range header {
-		message += fmt.Sprintf("%s: %s\r\n", k, v)
+// dial 按 TLSMode 建立连接并返回 SMTP 客户端，整个会话的读写截止时间为 SendTimeout 与 ctx 截止时间中较早者
+// 握手期间在内部监听 ctx 的取消，之后的会话由调用方通过 watchContext 监听
+func dial(ctx context.Context, cfg MailerConfig) (*smtp.Client, net.Conn, error) {
+	dialer := &net.Dialer{Timeout: cfg.DialTimeout}
+	var conn net.Conn
+	var err error
+	if cfg.TLSMode == TLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: cfg.tlsConfig()}).DialContext(ctx, "tcp", cfg.addr())
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", cfg.addr())
+	}
+	if err != nil {
+		//log.Println("Dialing Error:", err)
+		return nil, nil, sendErr(ctx, "dial", err)
 	}
-	message += "\r\n" + body
+	_ = conn.SetDeadline(sessionDeadline(ctx, cfg))
+	stop := watchContext(ctx, conn)
+	defer stop()
 
-	auth := smtp.PlainAuth("", defSender, password, host)
-	if err := sendMailUsingTLS(fmt.Sprintf("%s:%d", host, port), auth, defSender, address, []byte(message)); err != nil {
-		return false
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, sendErr(ctx, "dial", err)
+	}
+	if cfg.TLSMode == TLSStartTLS {
+		if err = c.StartTLS(cfg.tlsConfig()); err != nil {
+			_ = c.Close()
+			return nil, nil, sendErr(ctx, "dial", err)
+		}
 	}
-	return true
+	return c, conn, nil
 }
 
-func dial(addr string) (*smtp.Client, error) {
-	conn, err := tls.Dial("tcp", addr, nil)
-	if err != nil {
-		//log.Println("Dialing Error:", err)
-		return nil, err
+// sessionDeadline 本次会话的截止时间，取 SendTimeout 与 ctx 截止时间中较早者
+func sessionDeadline(ctx context.Context, cfg MailerConfig) time.Time {
+	deadline := time.Now().Add(cfg.SendTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	return deadline
+}
+
+// watchContext ctx 结束时将 conn 的截止时间提前到当前，使阻塞中的读写立即返回
+// 连接不在此处关闭，仍由调用方统一 Close；调用 stop 后停止监听
+func watchContext(ctx context.Context, conn net.Conn) (stop func()) {
+	if ctx.Done() == nil {
+		return func() {}
 	}
-	host, _, _ := net.SplitHostPort(addr)
-	return smtp.NewClient(conn, host)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
 }
 
-func sendMailUsingTLS(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
-	c, err := dial(addr)
+func sendMailWithConfig(ctx context.Context, cfg MailerConfig, from string, to []string, msg []byte) error {
+	c, conn, err := dial(ctx, cfg)
 	if err != nil {
 		//log.Println("Create smpt client error:", err)
 		return err
 	}
+	stop := watchContext(ctx, conn)
+	defer stop()
 	defer func(c *smtp.Client) {
 		if err = c.Close(); err != nil {
 			//log.Print("close err:", err)
 		}
 	}(c)
 
-	if auth != nil {
-		if ok, _ := c.Extension("AUTH"); ok {
-			if err = c.Auth(auth); err != nil {
-				//log.Println("Error during AUTH", err)
-				return err
-			}
+	if err = auth(ctx, c, cfg); err != nil {
+		return err
+	}
+	if err = send(ctx, c, from, to, msg); err != nil {
+		return err
+	}
+	// DATA 已被接受即已投递，QUIT 失败不返回错误，避免调用方重试导致重复发送
+	_ = c.Quit()
+	return nil
+}
+
+// auth 配置了密码且服务端支持时进行 AUTH
+func auth(ctx context.Context, c *smtp.Client, cfg MailerConfig) error {
+	if cfg.Password == "" {
+		return nil
+	}
+	if ok, _ := c.Extension("AUTH"); ok {
+		a := smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+		if err := c.Auth(a); err != nil {
+			//log.Println("Error during AUTH", err)
+			return sendErr(ctx, "auth", err)
 		}
 	}
+	return nil
+}
 
-	if err = c.Mail(from); err != nil {
-		return err
+// send 在已认证的会话上完成一次邮件投递，不发送 QUIT
+func send(ctx context.Context, c *smtp.Client, from string, to []string, msg []byte) error {
+	if err := c.Mail(from); err != nil {
+		return sendErr(ctx, "mail", err)
 	}
 
-	for _, addr = range to {
-		if err = c.Rcpt(addr); err != nil {
-			return err
+	// 逐个提交收件人并收集所有被拒绝的地址，有任一被拒绝时放弃本次投递
+	var rejected []RejectedRecipient
+	for _, addr := range to {
+		err := c.Rcpt(addr)
+		if err == nil {
+			continue
+		}
+		var tpErr *textproto.Error
+		if !errors.As(err, &tpErr) || ctx.Err() != nil {
+			return sendErr(ctx, "rcpt", err)
+		}
+		rejected = append(rejected, RejectedRecipient{Address: addr, Code: tpErr.Code, Message: tpErr.Msg})
+	}
+	if len(rejected) > 0 {
+		if err := c.Reset(); err != nil {
+			return errors.Join(&RecipientError{Rejected: rejected}, sendErr(ctx, "rset", err))
 		}
+		return &RecipientError{Rejected: rejected}
 	}
 
 	w, err := c.Data()
 	if err != nil {
-		return err
+		return sendErr(ctx, "data", err)
 	}
 
 	_, err = w.Write(msg)
 	if err != nil {
-		return err
+		return sendErr(ctx, "data", err)
 	}
 
 	err = w.Close()
 	if err != nil {
-		return err
+		return sendErr(ctx, "data", err)
 	}
-
-	return c.Quit()
+	return nil
 }
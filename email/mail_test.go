@@ -1,7 +1,12 @@
 package email_test
 
 import (
+	"context"
+	"errors"
+	"net"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/supernarsi/gotool/email"
 )
@@ -35,3 +40,164 @@ func TestIsEmailValid(t *testing.T) {
 		})
 	}
 }
+
+func TestSendMailUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	_ = ln.Close()
+
+	cfg := email.MailerConfig{
+		Host:        "127.0.0.1",
+		Port:        port,
+		FromAddress: "noreply@example.com",
+		TLSMode:     email.TLSNone,
+		DialTimeout: time.Second,
+	}
+	tests := []struct {
+		name   string
+		mailer email.Mail
+	}{
+		{"std", email.NewMailStd(cfg)},
+		{"gomail", email.NewMailGoMail(cfg)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.mailer.SendMail(context.Background(), []string{"to@example.com"}, "subject", "body")
+			if !errors.Is(err, email.ErrConnection) {
+				t.Errorf("got err %v, want %v", err, email.ErrConnection)
+			}
+		})
+	}
+}
+
+func TestSendMail(t *testing.T) {
+	srv := newFakeServer(t, func(s *fakeServer) { s.password = "secret" })
+	tests := []struct {
+		name   string
+		mailer email.Mail
+	}{
+		{"std", email.NewMailStd(srv.config())},
+		{"gomail", email.NewMailGoMail(srv.config())},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.mailer.SendMail(context.Background(), []string{"to@example.com"}, "subject", "body"); err != nil {
+				t.Fatal(err)
+			}
+			if got := len(srv.received()); got != i+1 {
+				t.Errorf("got result %v, want %v", got, i+1)
+			}
+		})
+	}
+}
+
+func TestSendMailQuitFailed(t *testing.T) {
+	srv := newFakeServer(t, func(s *fakeServer) { s.dropData = true })
+	tests := []struct {
+		name   string
+		mailer email.Mail
+	}{
+		{"std", email.NewMailStd(srv.config())},
+		{"gomail", email.NewMailGoMail(srv.config())},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// DATA 已被接受，QUIT 失败不应报告为可重试的错误
+			if err := tt.mailer.SendMail(context.Background(), []string{"to@example.com"}, "subject", "body"); err != nil {
+				t.Errorf("got err %v, want nil", err)
+			}
+			if got := len(srv.received()); got != i+1 {
+				t.Errorf("got result %v, want %v", got, i+1)
+			}
+		})
+	}
+}
+
+func TestSendMailAuthFailed(t *testing.T) {
+	srv := newFakeServer(t, func(s *fakeServer) { s.password = "secret" })
+	cfg := srv.config()
+	cfg.Password = "wrong"
+	tests := []struct {
+		name   string
+		mailer email.Mail
+	}{
+		{"std", email.NewMailStd(cfg)},
+		{"gomail", email.NewMailGoMail(cfg)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.mailer.SendMail(context.Background(), []string{"to@example.com"}, "subject", "body")
+			if !errors.Is(err, email.ErrAuth) {
+				t.Fatalf("got err %v, want %v", err, email.ErrAuth)
+			}
+			var sendErr *email.SendError
+			if !errors.As(err, &sendErr) || sendErr.Code != 535 || sendErr.Temporary() {
+				t.Errorf("got send error %+v", sendErr)
+			}
+		})
+	}
+}
+
+func TestSendMailRecipientRejected(t *testing.T) {
+	srv := newFakeServer(t, func(s *fakeServer) {
+		s.reject = map[string]int{"gone@example.com": 550, "full@example.com": 452}
+	})
+	m := email.NewMailStd(srv.config())
+	err := m.SendMail(context.Background(), []string{"gone@example.com", "ok@example.com", "full@example.com"}, "subject", "body")
+	if !errors.Is(err, email.ErrRecipientRejected) {
+		t.Fatalf("got err %v, want %v", err, email.ErrRecipientRejected)
+	}
+	var rcptErr *email.RecipientError
+	if !errors.As(err, &rcptErr) {
+		t.Fatalf("got err %T, want *email.RecipientError", err)
+	}
+	want := []email.RejectedRecipient{{Address: "gone@example.com", Code: 550}, {Address: "full@example.com", Code: 452}}
+	if len(rcptErr.Rejected) != len(want) {
+		t.Fatalf("got rejected %v, want %v", rcptErr.Rejected, want)
+	}
+	for i, r := range rcptErr.Rejected {
+		if r.Address != want[i].Address || r.Code != want[i].Code {
+			t.Errorf("got rejected %v, want %v", r, want[i])
+		}
+	}
+	if rcptErr.Temporary() {
+		t.Error("550 should not be temporary")
+	}
+	if len(srv.received()) != 0 {
+		t.Error("message should not be sent when any recipient is rejected")
+	}
+	if cmds := strings.Join(srv.commands(), " "); !strings.Contains(cmds, "RSET") || strings.Contains(cmds, "DATA") {
+		t.Errorf("got commands %v, want RSET without DATA", cmds)
+	}
+}
+
+func TestSendMailContextDeadline(t *testing.T) {
+	srv := newFakeServer(t, func(s *fakeServer) { s.silent = true })
+	m := email.NewMailStd(srv.config())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := m.SendMail(ctx, []string{"to@example.com"}, "subject", "body")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got err %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("send took %v, ctx deadline should be honored", d)
+	}
+}
+
+func TestSendMailContextCanceled(t *testing.T) {
+	srv := newFakeServer(t, func(s *fakeServer) { s.silent = true })
+	m := email.NewMailStd(srv.config())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	err := m.SendMail(ctx, []string{"to@example.com"}, "subject", "body")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got err %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,269 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrNoRecipient = errors.New("email: message has no recipient")
+
+// Message 一封邮件
+// Text 与 HTML 同时存在时以 multipart/alternative 发送；From 为空时使用发信实例配置的发件人
+type Message struct {
+	From        string            `json:"from,omitempty"`
+	To          []string          `json:"to,omitempty"`
+	Cc          []string          `json:"cc,omitempty"`
+	Bcc         []string          `json:"bcc,omitempty"`
+	ReplyTo     []string          `json:"reply_to,omitempty"`
+	Subject     string            `json:"subject"`
+	Text        string            `json:"text,omitempty"`
+	HTML        string            `json:"html,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"` // 额外的邮件头，如 List-Unsubscribe
+}
+
+// Attachment 附件；ContentID 非空时作为内联资源，HTML 中以 cid:ContentID 引用
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"` // 为空时按扩展名推断
+	ContentID   string `json:"content_id,omitempty"`
+	Data        []byte `json:"data"`
+}
+
+// Recipients 所有收件人（To、Cc、Bcc）的纯地址，已去重
+func (m *Message) Recipients() ([]string, error) {
+	seen := make(map[string]struct{})
+	var res []string
+	for _, list := range [][]string{m.To, m.Cc, m.Bcc} {
+		for _, s := range list {
+			addr, err := mail.ParseAddress(s)
+			if err != nil {
+				return nil, fmt.Errorf("email: invalid recipient %q: %w", s, err)
+			}
+			if _, ok := seen[addr.Address]; !ok {
+				seen[addr.Address] = struct{}{}
+				res = append(res, addr.Address)
+			}
+		}
+	}
+	if len(res) == 0 {
+		return nil, ErrNoRecipient
+	}
+	return res, nil
+}
+
+// envelope 返回信封发件人与收件人，msg.From 为空时使用 defFrom
+func (m *Message) envelope(defFrom string) (string, []string, error) {
+	fromHeader := m.From
+	if fromHeader == "" {
+		fromHeader = defFrom
+	}
+	from, err := mail.ParseAddress(fromHeader)
+	if err != nil {
+		return "", nil, fmt.Errorf("email: invalid sender %q: %w", fromHeader, err)
+	}
+	rcpts, err := m.Recipients()
+	if err != nil {
+		return "", nil, err
+	}
+	return from.Address, rcpts, nil
+}
+
+// Bytes 生成完整的 MIME 邮件内容（如保存为 .eml），From 不能为空
+func (m *Message) Bytes() ([]byte, error) {
+	return m.render("", time.Now())
+}
+
+// render 生成完整的 MIME 邮件内容，头部按 RFC 2047 编码，Bcc 不写入头部
+func (m *Message) render(defFrom string, now time.Time) ([]byte, error) {
+	fromHeader := m.From
+	if fromHeader == "" {
+		fromHeader = defFrom
+	}
+	from, err := mail.ParseAddress(fromHeader)
+	if err != nil {
+		return nil, fmt.Errorf("email: invalid sender %q: %w", fromHeader, err)
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", from.String())
+	for _, h := range []struct {
+		name string
+		list []string
+	}{{"To", m.To}, {"Cc", m.Cc}, {"Reply-To", m.ReplyTo}} {
+		if len(h.list) == 0 {
+			continue
+		}
+		value, err := formatAddressList(h.list)
+		if err != nil {
+			return nil, err
+		}
+		writeHeader(&buf, h.name, value)
+	}
+	writeHeader(&buf, "Subject", mime.BEncoding.Encode("UTF-8", m.Subject))
+	writeHeader(&buf, "Date", now.Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", newMessageID(from.Address))
+	writeHeader(&buf, "MIME-Version", "1.0")
+	extra := make([]string, 0, len(m.Headers))
+	for k := range m.Headers {
+		extra = append(extra, k)
+	}
+	sort.Strings(extra)
+	for _, k := range extra {
+		writeHeader(&buf, textproto.CanonicalMIMEHeaderKey(k), mime.QEncoding.Encode("UTF-8", m.Headers[k]))
+	}
+
+	body := m.body()
+	for _, k := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+		if v := body.header.Get(k); v != "" {
+			writeHeader(&buf, k, v)
+		}
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body.body)
+	return buf.Bytes(), nil
+}
+
+// mimeEntity 一个 MIME 实体的头部与内容
+type mimeEntity struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// body 组装邮件正文：mixed(related(alternative(text, html), inline...), attachment...)
+func (m *Message) body() mimeEntity {
+	var alternatives []mimeEntity
+	if m.Text != "" || m.HTML == "" {
+		alternatives = append(alternatives, textEntity("text/plain", m.Text))
+	}
+	if m.HTML != "" {
+		alternatives = append(alternatives, textEntity("text/html", m.HTML))
+	}
+	content := alternatives[0]
+	if len(alternatives) > 1 {
+		content = multipartEntity("alternative", alternatives)
+	}
+
+	var inline, attached []mimeEntity
+	for _, a := range m.Attachments {
+		if a.ContentID != "" {
+			inline = append(inline, a.entity())
+		} else {
+			attached = append(attached, a.entity())
+		}
+	}
+	if len(inline) > 0 {
+		content = multipartEntity("related", append([]mimeEntity{content}, inline...))
+	}
+	if len(attached) > 0 {
+		content = multipartEntity("mixed", append([]mimeEntity{content}, attached...))
+	}
+	return content
+}
+
+func textEntity(contentType string, text string) mimeEntity {
+	var buf bytes.Buffer
+	w := quotedprintable.NewWriter(&buf)
+	_, _ = w.Write([]byte(text))
+	_ = w.Close()
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", contentType+"; charset=UTF-8")
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+	return mimeEntity{header: h, body: buf.Bytes()}
+}
+
+func multipartEntity(subtype string, parts []mimeEntity) mimeEntity {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		pw, _ := mw.CreatePart(p.header)
+		_, _ = pw.Write(p.body)
+	}
+	_ = mw.Close()
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", mime.FormatMediaType("multipart/"+subtype, map[string]string{"boundary": mw.Boundary()}))
+	return mimeEntity{header: h, body: buf.Bytes()}
+}
+
+func (a Attachment) entity() mimeEntity {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(a.Filename))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Transfer-Encoding", "base64")
+	disposition := "attachment"
+	if a.ContentID != "" {
+		disposition = "inline"
+		h.Set("Content-ID", "<"+strings.Trim(a.ContentID, "<>")+">")
+	}
+	if a.Filename != "" {
+		h.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}))
+	} else {
+		h.Set("Content-Disposition", disposition)
+	}
+	return mimeEntity{header: h, body: base64Lines(a.Data)}
+}
+
+// base64Lines base64 编码并按 76 字符折行
+func base64Lines(data []byte) []byte {
+	const lineLen = 76
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var buf bytes.Buffer
+	for len(encoded) > lineLen {
+		buf.WriteString(encoded[:lineLen])
+		buf.WriteString("\r\n")
+		encoded = encoded[lineLen:]
+	}
+	buf.WriteString(encoded)
+	return buf.Bytes()
+}
+
+func formatAddressList(list []string) (string, error) {
+	addrs := make([]string, len(list))
+	for i, s := range list {
+		addr, err := mail.ParseAddress(s)
+		if err != nil {
+			return "", fmt.Errorf("email: invalid address %q: %w", s, err)
+		}
+		addrs[i] = addr.String()
+	}
+	return strings.Join(addrs, ", "), nil
+}
+
+func writeHeader(buf *bytes.Buffer, name string, value string) {
+	buf.WriteString(name)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteString("\r\n")
+}
+
+func newMessageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndexByte(from, '@'); i >= 0 {
+		domain = from[i+1:]
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
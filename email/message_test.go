@@ -0,0 +1,223 @@
+package email_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/supernarsi/gotool/email"
+)
+
+func parseMessage(t *testing.T, msg *email.Message) *mail.Message {
+	t.Helper()
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+// readParts 读取正文，返回各叶子部分的 Content-Type 与解码后的内容
+// multipart.Reader 会自动解码 quoted-printable，base64 需自行解码
+func readParts(t *testing.T, header mail.Header, body io.Reader) map[string]string {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := make(map[string]string)
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		if strings.EqualFold(header.Get("Content-Transfer-Encoding"), "base64") {
+			body = base64.NewDecoder(base64.StdEncoding, body)
+		}
+		b, _ := io.ReadAll(body)
+		res[mediaType] = string(b)
+		return res
+	}
+	mr := multipart.NewReader(body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range readParts(t, mail.Header(p.Header), p) {
+			res[k] = v
+		}
+		if cid := p.Header.Get("Content-ID"); cid != "" {
+			res["cid"] = cid
+		}
+	}
+	return res
+}
+
+func TestMessageHeaders(t *testing.T) {
+	msg := &email.Message{
+		From:    "系统通知 <noreply@example.com>",
+		To:      []string{"张三 <zhang@example.com>", "li@example.com"},
+		Cc:      []string{"cc@example.com"},
+		Bcc:     []string{"hidden@example.com"},
+		ReplyTo: []string{"support@example.com"},
+		Subject: "您的订单已发货",
+		Text:    "hello",
+		Headers: map[string]string{"List-Unsubscribe": "<mailto:unsub@example.com>"},
+	}
+	parsed := parseMessage(t, msg)
+
+	dec := new(mime.WordDecoder)
+	if got, _ := dec.DecodeHeader(parsed.Header.Get("Subject")); got != msg.Subject {
+		t.Errorf("got subject %v, want %v", got, msg.Subject)
+	}
+	if raw := parsed.Header.Get("Subject"); !strings.HasPrefix(raw, "=?UTF-8?b?") {
+		t.Errorf("subject should be RFC 2047 encoded, got %v", raw)
+	}
+	from, err := parsed.Header.AddressList("From")
+	if err != nil || from[0].Name != "系统通知" || from[0].Address != "noreply@example.com" {
+		t.Errorf("got from %v, err %v", from, err)
+	}
+	to, err := parsed.Header.AddressList("To")
+	if err != nil || len(to) != 2 || to[0].Name != "张三" {
+		t.Errorf("got to %v, err %v", to, err)
+	}
+	if parsed.Header.Get("Bcc") != "" {
+		t.Error("Bcc should not be written to headers")
+	}
+	for _, h := range []string{"Cc", "Reply-To", "Date", "Message-Id", "List-Unsubscribe"} {
+		if parsed.Header.Get(h) == "" {
+			t.Errorf("header %s should be set", h)
+		}
+	}
+
+	rcpts, err := msg.Recipients()
+	want := []string{"zhang@example.com", "li@example.com", "cc@example.com", "hidden@example.com"}
+	if err != nil || strings.Join(rcpts, ",") != strings.Join(want, ",") {
+		t.Errorf("got recipients %v, want %v", rcpts, want)
+	}
+}
+
+func TestMessageBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		msg       *email.Message
+		mediaType string
+		want      map[string]string
+	}{
+		{
+			name:      "text only",
+			msg:       &email.Message{Text: "纯文本"},
+			mediaType: "text/plain",
+			want:      map[string]string{"text/plain": "纯文本"},
+		},
+		{
+			name:      "html only",
+			msg:       &email.Message{HTML: "<p>hi</p>"},
+			mediaType: "text/html",
+			want:      map[string]string{"text/html": "<p>hi</p>"},
+		},
+		{
+			name:      "alternative",
+			msg:       &email.Message{Text: "hi", HTML: "<p>hi</p>"},
+			mediaType: "multipart/alternative",
+			want:      map[string]string{"text/plain": "hi", "text/html": "<p>hi</p>"},
+		},
+		{
+			name: "inline image",
+			msg: &email.Message{HTML: `<img src="cid:logo">`, Attachments: []email.Attachment{
+				{Filename: "logo.png", ContentID: "logo", Data: []byte("png-data")},
+			}},
+			mediaType: "multipart/related",
+			want:      map[string]string{"text/html": `<img src="cid:logo">`, "image/png": "png-data", "cid": "<logo>"},
+		},
+		{
+			name: "attachment",
+			msg: &email.Message{Text: "see attachment", HTML: "<p>see attachment</p>", Attachments: []email.Attachment{
+				{Filename: "报告.csv", ContentType: "text/csv", Data: []byte(strings.Repeat("a,b\n", 100))},
+			}},
+			mediaType: "multipart/mixed",
+			want:      map[string]string{"text/plain": "see attachment", "text/html": "<p>see attachment</p>", "text/csv": strings.Repeat("a,b\n", 100)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.msg.From = "noreply@example.com"
+			tt.msg.To = []string{"to@example.com"}
+			parsed := parseMessage(t, tt.msg)
+
+			mediaType, _, _ := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+			if mediaType != tt.mediaType {
+				t.Errorf("got content type %v, want %v", mediaType, tt.mediaType)
+			}
+
+			data, _ := tt.msg.Bytes()
+			got := decodeBody(t, data)
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("got %s %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+// decodeBody 将整封邮件包装为 multipart 的一个部分，以复用 multipart.Reader 的传输编码解码
+func decodeBody(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	const boundary = "test-boundary"
+	var buf bytes.Buffer
+	buf.WriteString("--" + boundary + "\r\n")
+	buf.Write(data)
+	buf.WriteString("\r\n--" + boundary + "--\r\n")
+	header := mail.Header{"Content-Type": {"multipart/mixed; boundary=" + boundary}}
+	return readParts(t, header, &buf)
+}
+
+func TestMessageAttachmentFilename(t *testing.T) {
+	msg := &email.Message{
+		From:        "noreply@example.com",
+		To:          []string{"to@example.com"},
+		Text:        "x",
+		Attachments: []email.Attachment{{Filename: "报告.pdf", Data: []byte("%PDF")}},
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("filename*=utf-8''%E6%8A%A5%E5%91%8A.pdf")) {
+		t.Errorf("non-ASCII filename should be RFC 2231 encoded:\n%s", data)
+	}
+	if !bytes.Contains(data, []byte("Content-Type: application/pdf")) {
+		t.Error("content type should be inferred from extension")
+	}
+}
+
+func TestMessageInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *email.Message
+	}{
+		{"no sender", &email.Message{To: []string{"to@example.com"}}},
+		{"bad recipient", &email.Message{From: "a@example.com", To: []string{"not an address"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.msg.Bytes(); err == nil {
+				t.Error("want error")
+			}
+		})
+	}
+	if _, err := (&email.Message{}).Recipients(); !errors.Is(err, email.ErrNoRecipient) {
+		t.Errorf("got err %v, want %v", err, email.ErrNoRecipient)
+	}
+}
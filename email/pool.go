@@ -0,0 +1,201 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/smtp"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPoolMaxConns    = 4
+	defaultPoolMaxMessages = 100
+	defaultPoolIdleTimeout = 30 * time.Second
+)
+
+var ErrPoolClosed = errors.New("email: pool is closed")
+
+// PoolConfig 连接池配置
+type PoolConfig struct {
+	MaxConns           int           // 最大连接数，默认 4
+	MaxMessagesPerConn int           // 单个连接最多投递的邮件数，达到后 QUIT 并重新建连，默认 100
+	IdleTimeout        time.Duration // 空闲超过该时长的连接不再复用，默认 30 秒
+}
+
+func (c PoolConfig) withDefaults() PoolConfig {
+	if c.MaxConns <= 0 {
+		c.MaxConns = defaultPoolMaxConns
+	}
+	if c.MaxMessagesPerConn <= 0 {
+		c.MaxMessagesPerConn = defaultPoolMaxMessages
+	}
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = defaultPoolIdleTimeout
+	}
+	return c
+}
+
+// MailPool 复用已认证 SMTP 会话的发信实现，适用于批量发送
+// 复用连接前先发送 RSET 确认会话可用；复用的连接返回 4xx 或断开时，自动新建连接重试一次
+type MailPool struct {
+	cfg  MailerConfig
+	pcfg PoolConfig
+	sem  chan struct{}
+
+	mu     sync.Mutex
+	idle   []*poolConn
+	closed bool
+}
+
+type poolConn struct {
+	c        *smtp.Client
+	conn     net.Conn
+	sent     int
+	lastUsed time.Time
+}
+
+// NewMailPool 基于 net/smtp 的连接池发信实例，使用完毕后需调用 Close
+func NewMailPool(cfg MailerConfig, pcfg PoolConfig) *MailPool {
+	pcfg = pcfg.withDefaults()
+	return &MailPool{
+		cfg:  cfg.withDefaults(),
+		pcfg: pcfg,
+		sem:  make(chan struct{}, pcfg.MaxConns),
+	}
+}
+
+func (p *MailPool) SendMail(ctx context.Context, address []string, subject string, body string) error {
+	return p.Send(ctx, &Message{To: address, Subject: subject, HTML: body})
+}
+
+// Send 发送完整的邮件，连接数已满时等待空闲连接或 ctx 结束
+func (p *MailPool) Send(ctx context.Context, msg *Message) error {
+	from, rcpts, data, err := p.cfg.prepare(msg)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return &SendError{Op: "dial", Err: ctx.Err()}
+	}
+	defer func() { <-p.sem }()
+
+	for attempt := 0; ; attempt++ {
+		pc, reused, err := p.get(ctx)
+		if err != nil {
+			return err
+		}
+		err = pc.send(ctx, p.cfg, from, rcpts, data)
+		var rcptErr *RecipientError
+		var sendErr *SendError
+		if err == nil || errors.As(err, &rcptErr) && !errors.As(err, &sendErr) {
+			// 收件人被拒绝且 RSET 成功时会话仍可复用；RSET 失败时错误中同时包含 SendError，需断开
+			if err == nil {
+				pc.sent++
+			}
+			p.put(pc)
+			return err
+		}
+		_ = pc.conn.Close()
+		if rcptErr != nil || !reused || attempt > 0 || !retryable(err) {
+			return err
+		}
+	}
+}
+
+// retryable 复用连接上的失败是否可换新连接重试
+// DATA 阶段连接中断时服务端可能已收下邮件，不重试以免重复投递
+func retryable(err error) bool {
+	var e *SendError
+	if !errors.As(err, &e) || !e.Temporary() {
+		return false
+	}
+	return e.Op != "data" || e.Code != 0
+}
+
+// get 取出可用的空闲连接，没有时新建连接并完成 AUTH
+func (p *MailPool) get(ctx context.Context) (pc *poolConn, reused bool, err error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, false, ErrPoolClosed
+		}
+		n := len(p.idle)
+		if n == 0 {
+			p.mu.Unlock()
+			break
+		}
+		pc = p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+
+		if time.Since(pc.lastUsed) > p.pcfg.IdleTimeout {
+			pc.quit()
+			continue
+		}
+		_ = pc.conn.SetDeadline(sessionDeadline(ctx, p.cfg))
+		if err = pc.c.Reset(); err != nil {
+			_ = pc.conn.Close()
+			continue
+		}
+		return pc, true, nil
+	}
+
+	c, conn, err := dial(ctx, p.cfg)
+	if err != nil {
+		return nil, false, err
+	}
+	stop := watchContext(ctx, conn)
+	err = auth(ctx, c, p.cfg)
+	stop()
+	if err != nil {
+		_ = c.Close()
+		return nil, false, err
+	}
+	return &poolConn{c: c, conn: conn}, false, nil
+}
+
+// put 归还连接，达到单连接邮件数上限或连接池已关闭时断开
+func (p *MailPool) put(pc *poolConn) {
+	pc.lastUsed = time.Now()
+	p.mu.Lock()
+	if !p.closed && pc.sent < p.pcfg.MaxMessagesPerConn {
+		p.idle = append(p.idle, pc)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	pc.quit()
+}
+
+// Close 断开所有空闲连接，正在使用的连接在本次投递结束后断开
+func (p *MailPool) Close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mu.Unlock()
+	for _, pc := range idle {
+		pc.quit()
+	}
+	return nil
+}
+
+func (pc *poolConn) send(ctx context.Context, cfg MailerConfig, from string, to []string, msg []byte) error {
+	_ = pc.conn.SetDeadline(sessionDeadline(ctx, cfg))
+	stop := watchContext(ctx, pc.conn)
+	defer stop()
+	return send(ctx, pc.c, from, to, msg)
+}
+
+// quit 尽力发送 QUIT 后关闭连接
+func (pc *poolConn) quit() {
+	_ = pc.conn.SetDeadline(time.Now().Add(time.Second))
+	_ = pc.c.Quit()
+	_ = pc.c.Close()
+}
@@ -0,0 +1,155 @@
+package email_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/supernarsi/gotool/email"
+)
+
+func countCommand(cmds []string, verb string) int {
+	n := 0
+	for _, c := range cmds {
+		if c == verb {
+			n++
+		}
+	}
+	return n
+}
+
+func TestMailPoolReuse(t *testing.T) {
+	srv := newFakeServer(t, func(s *fakeServer) { s.password = "secret" })
+	pool := email.NewMailPool(srv.config(), email.PoolConfig{MaxConns: 1, MaxMessagesPerConn: 4})
+	defer pool.Close()
+
+	for i := 0; i < 10; i++ {
+		to := fmt.Sprintf("user%d@example.com", i)
+		if err := pool.SendMail(context.Background(), []string{to}, "digest", "body"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(srv.received()); got != 10 {
+		t.Errorf("got result %v, want %v", got, 10)
+	}
+	conns, _ := srv.stats()
+	if conns != 3 {
+		t.Errorf("got conns %v, want %v", conns, 3)
+	}
+	cmds := srv.commands()
+	if got := countCommand(cmds, "AUTH"); got != 3 {
+		t.Errorf("got auth %v, want %v", got, 3)
+	}
+	// 每个连接上除第一封外每封前都有一次 RSET
+	if got := countCommand(cmds, "RSET"); got != 7 {
+		t.Errorf("got rset %v, want %v", got, 7)
+	}
+}
+
+func TestMailPoolMaxConns(t *testing.T) {
+	srv := newFakeServer(t, nil)
+	pool := email.NewMailPool(srv.config(), email.PoolConfig{MaxConns: 2})
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- pool.SendMail(context.Background(), []string{"to@example.com"}, "subject", "body")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(srv.received()); got != 20 {
+		t.Errorf("got result %v, want %v", got, 20)
+	}
+	if _, maxActive := srv.stats(); maxActive > 2 {
+		t.Errorf("got max active conns %v, want <= %v", maxActive, 2)
+	}
+}
+
+func TestMailPoolReconnect(t *testing.T) {
+	// 服务端每个连接只接受一封邮件，之后的 MAIL 返回 421 并断开
+	srv := newFakeServer(t, func(s *fakeServer) { s.perConn = 1 })
+	pool := email.NewMailPool(srv.config(), email.PoolConfig{MaxConns: 1})
+	defer pool.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := pool.SendMail(context.Background(), []string{"to@example.com"}, "subject", "body"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(srv.received()); got != 3 {
+		t.Errorf("got result %v, want %v", got, 3)
+	}
+	if conns, _ := srv.stats(); conns != 3 {
+		t.Errorf("got conns %v, want %v", conns, 3)
+	}
+}
+
+func TestMailPoolRecipientRejected(t *testing.T) {
+	srv := newFakeServer(t, func(s *fakeServer) { s.reject = map[string]int{"gone@example.com": 550} })
+	pool := email.NewMailPool(srv.config(), email.PoolConfig{MaxConns: 1})
+	defer pool.Close()
+
+	err := pool.SendMail(context.Background(), []string{"gone@example.com"}, "subject", "body")
+	if !errors.Is(err, email.ErrRecipientRejected) {
+		t.Fatalf("got err %v, want %v", err, email.ErrRecipientRejected)
+	}
+	if err = pool.SendMail(context.Background(), []string{"to@example.com"}, "subject", "body"); err != nil {
+		t.Fatal(err)
+	}
+	if conns, _ := srv.stats(); conns != 1 {
+		t.Errorf("got conns %v, want %v", conns, 1)
+	}
+}
+
+func TestMailPoolRecipientRejectedResetFailed(t *testing.T) {
+	srv := newFakeServer(t, func(s *fakeServer) {
+		s.reject = map[string]int{"gone@example.com": 550}
+		s.failRset = true
+	})
+	pool := email.NewMailPool(srv.config(), email.PoolConfig{MaxConns: 1})
+	defer pool.Close()
+
+	err := pool.SendMail(context.Background(), []string{"gone@example.com"}, "subject", "body")
+	var sendErr *email.SendError
+	if !errors.Is(err, email.ErrRecipientRejected) || !errors.As(err, &sendErr) || sendErr.Op != "rset" {
+		t.Fatalf("got err %v, want recipient rejected and rset error", err)
+	}
+	// RSET 失败的连接不再复用
+	if err = pool.SendMail(context.Background(), []string{"to@example.com"}, "subject", "body"); err != nil {
+		t.Fatal(err)
+	}
+	if conns, _ := srv.stats(); conns != 2 {
+		t.Errorf("got conns %v, want %v", conns, 2)
+	}
+}
+
+func TestMailPoolClose(t *testing.T) {
+	srv := newFakeServer(t, nil)
+	pool := email.NewMailPool(srv.config(), email.PoolConfig{})
+	if err := pool.SendMail(context.Background(), []string{"to@example.com"}, "subject", "body"); err != nil {
+		t.Fatal(err)
+	}
+	_ = pool.Close()
+	err := pool.SendMail(context.Background(), []string{"to@example.com"}, "subject", "body")
+	if !errors.Is(err, email.ErrPoolClosed) {
+		t.Errorf("got err %v, want %v", err, email.ErrPoolClosed)
+	}
+	if !strings.Contains(strings.Join(srv.commands(), " "), "QUIT") {
+		t.Error("idle connections should QUIT on Close")
+	}
+}
+
+var _ email.Mail = (*email.MailPool)(nil)
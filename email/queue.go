@@ -0,0 +1,371 @@
+package email
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	mrand "math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	defaultQueueWorkers     = 4
+	defaultQueueSize        = 1000
+	defaultQueueMaxAttempts = 5
+	defaultQueueBackoff     = time.Second
+	defaultQueueMaxBackoff  = 5 * time.Minute
+)
+
+var (
+	ErrQueueClosed = errors.New("email: queue is closed")
+	ErrQueueFull   = errors.New("email: queue is full")
+)
+
+// QueuedMessage 队列中的一封邮件
+type QueuedMessage struct {
+	ID         string    `json:"id"`
+	Message    *Message  `json:"message"`
+	Attempts   int       `json:"attempts"` // 已尝试发送的次数
+	LastError  string    `json:"last_error,omitempty"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
+
+// Store 队列持久化，使尚未发送的邮件在重启后继续发送
+type Store interface {
+	// Save 入队及每次重试前保存
+	Save(m *QueuedMessage) error
+	// Delete 发送成功或进入死信后删除
+	Delete(id string) error
+	// Load 队列启动时加载上次未完成的邮件
+	Load() ([]*QueuedMessage, error)
+}
+
+// QueueConfig 队列配置
+type QueueConfig struct {
+	Workers        int           // 并发发送的 worker 数，默认 4
+	Size           int           // 排队与等待重试的邮件总数上限，默认 1000
+	MaxAttempts    int           // 最多发送次数，默认 5
+	InitialBackoff time.Duration // 首次重试的等待时间，之后每次翻倍，默认 1 秒
+	MaxBackoff     time.Duration // 重试等待时间上限，默认 5 分钟
+	SendTimeout    time.Duration // 单次发送的超时，为 0 时仅受 Mail 实现自身的超时限制
+	Store          Store         // 为 nil 时不持久化
+
+	// OnStoreError Store 保存或删除失败，为 nil 时忽略；重试前保存失败时邮件仍在内存中继续重试
+	OnStoreError func(m *QueuedMessage, err error)
+
+	// OnDeadLetter 放弃发送的邮件：永久错误、达到最多发送次数，
+	// 或未配置 Store 时 Shutdown 时仍在等待重试、ctx 结束时仍在排队或发送被中断（err 为 ErrQueueClosed）
+	OnDeadLetter func(m *QueuedMessage, err error)
+}
+
+func (c QueueConfig) withDefaults() QueueConfig {
+	if c.Workers <= 0 {
+		c.Workers = defaultQueueWorkers
+	}
+	if c.Size <= 0 {
+		c.Size = defaultQueueSize
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaultQueueMaxAttempts
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = defaultQueueBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaultQueueMaxBackoff
+	}
+	return c
+}
+
+// Queue 异步发信队列
+// 临时错误（4xx、网络错误）按指数退避重试，其余错误直接进入死信
+type Queue struct {
+	mailer Mail
+	cfg    QueueConfig
+
+	// abort 在 Shutdown 超时后取消仍在进行的发送
+	abortCtx context.Context
+	abort    context.CancelFunc
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	ready   []*QueuedMessage
+	delayed map[string]*time.Timer
+	waiting map[string]*QueuedMessage
+	sending map[string]*QueuedMessage // 正在发送的邮件，Shutdown 超时后由其接管
+	saving  int                       // 已占用名额、正在写入 Store 的邮件数
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// NewQueue 创建并启动队列，配置了 Store 时先加载上次未完成的邮件
+func NewQueue(mailer Mail, cfg QueueConfig) (*Queue, error) {
+	cfg = cfg.withDefaults()
+	q := &Queue{
+		mailer:  mailer,
+		cfg:     cfg,
+		delayed: make(map[string]*time.Timer),
+		waiting: make(map[string]*QueuedMessage),
+		sending: make(map[string]*QueuedMessage),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	q.abortCtx, q.abort = context.WithCancel(context.Background())
+	if cfg.Store != nil {
+		pending, err := cfg.Store.Load()
+		if err != nil {
+			return nil, err
+		}
+		q.ready = append(q.ready, pending...)
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q, nil
+}
+
+// Enqueue 将邮件加入队列并立即返回 ID，不等待发送
+// 写入 Store 时不持有队列的锁，先占用名额，保存失败时释放
+func (q *Queue) Enqueue(msg *Message) (string, error) {
+	m := &QueuedMessage{ID: newQueueID(), Message: msg, EnqueuedAt: time.Now()}
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return "", ErrQueueClosed
+	}
+	if len(q.ready)+len(q.waiting)+q.saving >= q.cfg.Size {
+		q.mu.Unlock()
+		return "", ErrQueueFull
+	}
+	q.saving++
+	q.mu.Unlock()
+
+	var err error
+	if q.cfg.Store != nil {
+		err = q.cfg.Store.Save(m)
+	}
+
+	q.mu.Lock()
+	q.saving--
+	if err != nil {
+		q.mu.Unlock()
+		return "", err
+	}
+	if q.closed {
+		q.mu.Unlock()
+		// 保存期间队列已关闭，撤销保存，避免下次启动时发送调用方认为失败的邮件
+		q.remove(m)
+		return "", ErrQueueClosed
+	}
+	q.ready = append(q.ready, m)
+	q.cond.Signal()
+	q.mu.Unlock()
+	return m.ID, nil
+}
+
+// Len 排队与等待重试的邮件数
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.ready) + len(q.waiting)
+}
+
+// Shutdown 停止接收新邮件，等待已排队的邮件发送完毕
+// 等待重试的邮件不再发送，配置了 Store 时保留到下次启动
+// ctx 结束时取消正在进行的发送并立即返回 ctx.Err()，不等待忽略 ctx 的 Mail 实现返回；
+// 此时仍在排队与正在发送的邮件不再重试，未配置 Store 时交给 OnDeadLetter，不会静默丢弃
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrQueueClosed
+	}
+	q.closed = true
+	var dropped []*QueuedMessage
+	for id, timer := range q.delayed {
+		timer.Stop()
+		dropped = append(dropped, q.waiting[id])
+	}
+	q.delayed = make(map[string]*time.Timer)
+	q.waiting = make(map[string]*QueuedMessage)
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	if q.cfg.Store == nil {
+		for _, m := range dropped {
+			q.deadLetter(m, ErrQueueClosed)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		q.abort()
+		return nil
+	case <-ctx.Done():
+		q.abort()
+		q.mu.Lock()
+		pending := q.ready
+		q.ready = nil
+		for id, m := range q.sending {
+			pending = append(pending, m)
+			delete(q.sending, id)
+		}
+		q.mu.Unlock()
+		if q.cfg.Store == nil {
+			for _, m := range pending {
+				q.deadLetter(m, ErrQueueClosed)
+			}
+		}
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for {
+		q.mu.Lock()
+		for len(q.ready) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.ready) == 0 || q.abortCtx.Err() != nil {
+			q.mu.Unlock()
+			return
+		}
+		m := q.ready[0]
+		q.ready[0] = nil
+		q.ready = q.ready[1:]
+		m.Attempts++
+		q.sending[m.ID] = m
+		q.mu.Unlock()
+
+		q.process(m)
+	}
+}
+
+func (q *Queue) process(m *QueuedMessage) {
+	ctx := q.abortCtx
+	if q.cfg.SendTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, q.cfg.SendTimeout)
+		defer cancel()
+	}
+	err := q.mailer.Send(ctx, m.Message)
+	owned := q.finish(m)
+	if err == nil {
+		q.remove(m)
+		return
+	}
+	if !owned {
+		// Shutdown 超时后已接管
+		return
+	}
+	if q.abortCtx.Err() != nil {
+		// Shutdown 超时中断的发送不计入次数，保留在 Store 中
+		m.Attempts--
+		if q.cfg.Store == nil {
+			q.deadLetter(m, ErrQueueClosed)
+		}
+		return
+	}
+	m.LastError = err.Error()
+	// 单次发送超时同样视为临时错误
+	temporary := isTemporary(err) || errors.Is(err, context.DeadlineExceeded)
+	if !temporary || m.Attempts >= q.cfg.MaxAttempts {
+		q.deadLetter(m, err)
+		return
+	}
+	q.retry(m)
+}
+
+// finish 结束发送，返回邮件是否仍由 worker 处理
+func (q *Queue) finish(m *QueuedMessage) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_, ok := q.sending[m.ID]
+	delete(q.sending, m.ID)
+	return ok
+}
+
+// retry 按退避时间延迟后重新排队
+func (q *Queue) retry(m *QueuedMessage) {
+	if q.cfg.Store != nil {
+		if err := q.cfg.Store.Save(m); err != nil {
+			q.storeError(m, err)
+		}
+	}
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		if q.cfg.Store == nil {
+			q.deadLetter(m, ErrQueueClosed)
+		}
+		return
+	}
+	defer q.mu.Unlock()
+	q.waiting[m.ID] = m
+	q.delayed[m.ID] = time.AfterFunc(q.backoff(m.Attempts), func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if _, ok := q.delayed[m.ID]; !ok {
+			return
+		}
+		delete(q.delayed, m.ID)
+		delete(q.waiting, m.ID)
+		q.ready = append(q.ready, m)
+		q.cond.Signal()
+	})
+}
+
+// backoff 第 attempts 次失败后的等待时间，在 [d/2, d) 之间随机以错开重试
+func (q *Queue) backoff(attempts int) time.Duration {
+	d := q.cfg.InitialBackoff
+	for i := 1; i < attempts && d < q.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > q.cfg.MaxBackoff {
+		d = q.cfg.MaxBackoff
+	}
+	half := d / 2
+	return half + time.Duration(mrand.Int63n(int64(half)+1))
+}
+
+func (q *Queue) deadLetter(m *QueuedMessage, err error) {
+	q.remove(m)
+	if q.cfg.OnDeadLetter != nil {
+		q.cfg.OnDeadLetter(m, err)
+	}
+}
+
+func (q *Queue) remove(m *QueuedMessage) {
+	if q.cfg.Store != nil {
+		if err := q.cfg.Store.Delete(m.ID); err != nil {
+			q.storeError(m, err)
+		}
+	}
+}
+
+func (q *Queue) storeError(m *QueuedMessage, err error) {
+	if q.cfg.OnStoreError != nil {
+		q.cfg.OnStoreError(m, err)
+	}
+}
+
+// isTemporary 错误是否可重试，支持任意实现了 Temporary() bool 的错误
+func isTemporary(err error) bool {
+	var t interface{ Temporary() bool }
+	return errors.As(err, &t) && t.Temporary()
+}
+
+func newQueueID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
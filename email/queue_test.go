@@ -0,0 +1,457 @@
+package email_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/supernarsi/gotool/email"
+)
+
+// scriptMailer 依次返回 errs 中的错误，用完后发送成功
+type scriptMailer struct {
+	mu    sync.Mutex
+	errs  []error
+	delay time.Duration
+	calls int
+	sent  []*email.Message
+}
+
+func (m *scriptMailer) SendMail(ctx context.Context, address []string, subject string, body string) error {
+	return m.Send(ctx, &email.Message{To: address, Subject: subject, HTML: body})
+}
+
+func (m *scriptMailer) Send(ctx context.Context, msg *email.Message) error {
+	if m.delay > 0 {
+		select {
+		case <-time.After(m.delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	if len(m.errs) > 0 {
+		err := m.errs[0]
+		m.errs = m.errs[1:]
+		return err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *scriptMailer) result() (calls int, sent int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls, len(m.sent)
+}
+
+// deadLetters 记录进入死信的邮件
+type deadLetters struct {
+	mu   sync.Mutex
+	msgs []*email.QueuedMessage
+	errs []error
+}
+
+func (d *deadLetters) add(m *email.QueuedMessage, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.msgs = append(d.msgs, m)
+	d.errs = append(d.errs, err)
+}
+
+func (d *deadLetters) len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.msgs)
+}
+
+var errTemporary = &email.SendError{Op: "mail", Code: 451, Err: errors.New("try again later")}
+
+func newMessage() *email.Message {
+	return &email.Message{From: "noreply@example.com", To: []string{"to@example.com"}, Subject: "subject", Text: "body"}
+}
+
+func TestQueueRetry(t *testing.T) {
+	permanent := &email.SendError{Op: "mail", Code: 550, Err: errors.New("mailbox unavailable")}
+	tests := []struct {
+		name         string
+		errs         []error
+		wantCalls    int
+		wantSent     int
+		wantAttempts int // 进入死信时的发送次数，0 表示不进入死信
+	}{
+		{"success", nil, 1, 1, 0},
+		{"temporary then success", []error{errTemporary, errTemporary}, 3, 1, 0},
+		{"permanent", []error{permanent}, 1, 0, 1},
+		{"max attempts", []error{errTemporary, errTemporary, errTemporary}, 3, 0, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mailer := &scriptMailer{errs: tt.errs}
+			dead := new(deadLetters)
+			q, err := email.NewQueue(mailer, email.QueueConfig{
+				Workers:        1,
+				MaxAttempts:    3,
+				InitialBackoff: 10 * time.Millisecond,
+				OnDeadLetter:   dead.add,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = q.Enqueue(newMessage()); err != nil {
+				t.Fatal(err)
+			}
+
+			deadline := time.Now().Add(2 * time.Second)
+			for time.Now().Before(deadline) {
+				if calls, _ := mailer.result(); calls >= tt.wantCalls && q.Len() == 0 {
+					break
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			if err = q.Shutdown(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			calls, sent := mailer.result()
+			if calls != tt.wantCalls || sent != tt.wantSent {
+				t.Errorf("got calls %v sent %v, want %v %v", calls, sent, tt.wantCalls, tt.wantSent)
+			}
+			if tt.wantAttempts == 0 {
+				if dead.len() != 0 {
+					t.Errorf("got dead letters %v, want none", dead.errs)
+				}
+				return
+			}
+			if dead.len() != 1 || dead.msgs[0].Attempts != tt.wantAttempts || dead.msgs[0].LastError == "" {
+				t.Fatalf("got dead letters %+v, want one after %v attempts", dead.msgs, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestQueueShutdownDrains(t *testing.T) {
+	mailer := &scriptMailer{delay: 20 * time.Millisecond}
+	q, err := email.NewQueue(mailer, email.QueueConfig{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		if _, err = q.Enqueue(newMessage()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = q.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, sent := mailer.result(); sent != 6 {
+		t.Errorf("got result %v, want %v", sent, 6)
+	}
+	if _, err = q.Enqueue(newMessage()); !errors.Is(err, email.ErrQueueClosed) {
+		t.Errorf("got err %v, want %v", err, email.ErrQueueClosed)
+	}
+}
+
+func TestQueueShutdownTimeout(t *testing.T) {
+	mailer := &scriptMailer{delay: time.Minute}
+	q, err := email.NewQueue(mailer, email.QueueConfig{Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = q.Enqueue(newMessage())
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err = q.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got err %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestQueueShutdownTimeoutDeadLetters(t *testing.T) {
+	dead := &deadLetters{}
+	mailer := &scriptMailer{delay: time.Minute}
+	q, err := email.NewQueue(mailer, email.QueueConfig{Workers: 1, OnDeadLetter: dead.add})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err = q.Enqueue(newMessage()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	// 发送中与仍在排队的邮件都进入死信
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err = q.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got err %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := dead.len(); got != 5 {
+		t.Errorf("got result %v, want %v", got, 5)
+	}
+	for _, err := range dead.errs {
+		if !errors.Is(err, email.ErrQueueClosed) {
+			t.Errorf("got err %v, want %v", err, email.ErrQueueClosed)
+		}
+	}
+	if got := q.Len(); got != 0 {
+		t.Errorf("got result %v, want %v", got, 0)
+	}
+}
+
+// stuckMailer 忽略 ctx，阻塞到 release 关闭
+type stuckMailer struct {
+	release chan struct{}
+}
+
+func (m *stuckMailer) SendMail(ctx context.Context, address []string, subject string, body string) error {
+	return m.Send(ctx, &email.Message{To: address, Subject: subject, HTML: body})
+}
+
+func (m *stuckMailer) Send(ctx context.Context, msg *email.Message) error {
+	<-m.release
+	return nil
+}
+
+func TestQueueShutdownIgnoresStuckMailer(t *testing.T) {
+	dead := &deadLetters{}
+	mailer := &stuckMailer{release: make(chan struct{})}
+	defer close(mailer.release)
+	q, err := email.NewQueue(mailer, email.QueueConfig{Workers: 1, OnDeadLetter: dead.add})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err = q.Enqueue(newMessage()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	// Mail 实现不响应 ctx 时 Shutdown 仍按时返回
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err = q.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got err %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Shutdown took %v, want about 50ms", elapsed)
+	}
+	if got := dead.len(); got != 3 {
+		t.Errorf("got result %v, want %v", got, 3)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	mailer := &scriptMailer{delay: time.Minute}
+	q, err := email.NewQueue(mailer, email.QueueConfig{Workers: 1, Size: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_ = q.Shutdown(ctx)
+	}()
+
+	// 第一封被 worker 取走后队列中可再放两封
+	_, _ = q.Enqueue(newMessage())
+	time.Sleep(10 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if _, err = q.Enqueue(newMessage()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = q.Enqueue(newMessage()); !errors.Is(err, email.ErrQueueFull) {
+		t.Errorf("got err %v, want %v", err, email.ErrQueueFull)
+	}
+}
+
+func TestQueuePersistence(t *testing.T) {
+	dir := t.TempDir()
+	store, err := email.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一次发送临时失败，等待重试期间关闭队列，邮件保留在 Store 中
+	failing := &scriptMailer{errs: []error{errTemporary}}
+	q, err := email.NewQueue(failing, email.QueueConfig{Workers: 1, InitialBackoff: time.Hour, Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := newMessage()
+	msg.Attachments = []email.Attachment{{Filename: "a.txt", Data: []byte("attachment")}}
+	id, err := q.Enqueue(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for calls, _ := failing.result(); calls == 0 || q.Len() == 0; calls, _ = failing.result() {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err = q.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(dir + "/" + id + ".json"); err != nil {
+		t.Fatalf("queued message should be persisted: %v", err)
+	}
+
+	// 重启后继续发送
+	mailer := new(scriptMailer)
+	q, err = email.NewQueue(mailer, email.QueueConfig{Workers: 1, Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = q.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, sent := mailer.result(); sent != 1 {
+		t.Fatalf("got result %v, want %v", sent, 1)
+	}
+	if got := string(mailer.sent[0].Attachments[0].Data); got != "attachment" {
+		t.Errorf("got attachment %v, want %v", got, "attachment")
+	}
+	pending, err := store.Load()
+	if err != nil || len(pending) != 0 {
+		t.Errorf("got pending %v, err %v, want empty", pending, err)
+	}
+}
+
+// memStore 内存中的 Store，Save 可阻塞或返回错误
+type memStore struct {
+	mu      sync.Mutex
+	msgs    map[string]*email.QueuedMessage
+	block   chan struct{} // 非 nil 时 Save 阻塞到其关闭
+	saveErr error
+}
+
+func (s *memStore) Save(m *email.QueuedMessage) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saveErr != nil {
+		return s.saveErr
+	}
+	if s.msgs == nil {
+		s.msgs = make(map[string]*email.QueuedMessage)
+	}
+	s.msgs[m.ID] = m
+	return nil
+}
+
+func (s *memStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.msgs, id)
+	return nil
+}
+
+func (s *memStore) Load() ([]*email.QueuedMessage, error) { return nil, nil }
+
+func (s *memStore) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saveErr = err
+}
+
+func TestQueueStoreSave(t *testing.T) {
+	// 写入 Store 时不阻塞队列的其他操作
+	store := &memStore{block: make(chan struct{})}
+	q, err := email.NewQueue(new(scriptMailer), email.QueueConfig{Workers: 1, Size: 1, Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enqueued := make(chan error, 1)
+	go func() {
+		_, err := q.Enqueue(newMessage())
+		enqueued <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	lenDone := make(chan int, 1)
+	go func() { lenDone <- q.Len() }()
+	select {
+	case <-lenDone:
+	case <-time.After(time.Second):
+		t.Fatal("Len should not wait for Store.Save")
+	}
+	// 正在保存的邮件占用名额
+	if _, err = q.Enqueue(newMessage()); !errors.Is(err, email.ErrQueueFull) {
+		t.Errorf("got err %v, want %v", err, email.ErrQueueFull)
+	}
+	close(store.block)
+	if err = <-enqueued; err != nil {
+		t.Fatal(err)
+	}
+	if err = q.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 保存失败时释放名额
+	errSave := errors.New("disk full")
+	store = &memStore{saveErr: errSave}
+	q, err = email.NewQueue(&scriptMailer{delay: time.Minute}, email.QueueConfig{Workers: 1, Size: 1, Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_ = q.Shutdown(ctx)
+	}()
+	for i := 0; i < 2; i++ {
+		if _, err = q.Enqueue(newMessage()); !errors.Is(err, errSave) {
+			t.Errorf("got err %v, want %v", err, errSave)
+		}
+	}
+	if got := q.Len(); got != 0 {
+		t.Errorf("got result %v, want %v", got, 0)
+	}
+}
+
+func TestQueueRetrySaveError(t *testing.T) {
+	errSave := errors.New("disk full")
+	store := &memStore{}
+	var mu sync.Mutex
+	var storeErrs []error
+	mailer := &scriptMailer{errs: []error{errTemporary}}
+	q, err := email.NewQueue(mailer, email.QueueConfig{
+		Workers:        1,
+		InitialBackoff: 10 * time.Millisecond,
+		Store:          store,
+		OnStoreError: func(m *email.QueuedMessage, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			storeErrs = append(storeErrs, err)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = q.Enqueue(newMessage()); err != nil {
+		t.Fatal(err)
+	}
+	store.fail(errSave)
+
+	// 重试前保存失败时上报错误，邮件仍在内存中重试
+	for _, sent := mailer.result(); sent == 0; _, sent = mailer.result() {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err = q.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(storeErrs) != 1 || !errors.Is(storeErrs[0], errSave) {
+		t.Errorf("got store errors %v, want [%v]", storeErrs, errSave)
+	}
+}
@@ -0,0 +1,271 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailureThreshold = 3
+	defaultCooldown         = time.Minute
+)
+
+var (
+	ErrNoProvider  = errors.New("email: no available provider")
+	ErrRateLimited = errors.New("email: provider rate limited")
+)
+
+// Provider 一个发信渠道
+type Provider struct {
+	Name          string
+	Mail          Mail
+	RatePerSecond float64 // 每秒最多发送数，0 表示不限制
+	Burst         int     // 令牌桶容量，默认为 RatePerSecond 向上取整
+	DailyQuota    int     // 每个自然日最多发送数，0 表示不限制
+}
+
+// RouterConfig 多渠道发信配置
+type RouterConfig struct {
+	Providers        []Provider          // 按优先级排列
+	FailureThreshold int                 // 连续失败多少次后暂停使用该渠道，默认 3
+	Cooldown         time.Duration       // 暂停时长，之后允许再次尝试，成功即恢复，默认 1 分钟
+	Rules            map[string][]string // 收件人域名（含子域名）到渠道名称的路由，匹配时仅使用这些渠道
+	Location         *time.Location      // 每日配额的日期边界，默认 time.Local
+}
+
+// ProviderStatus 渠道当前状态
+type ProviderStatus struct {
+	Name      string
+	Healthy   bool
+	Failures  int       // 连续失败次数
+	Until     time.Time // 暂停到该时间，Healthy 为 true 时为零值
+	SentToday int
+}
+
+// Router 按优先级依次尝试多个渠道的发信实现
+// 渠道返回 *SendError（连接、认证与服务端响应错误）时计入失败并切换到下一个渠道；
+// 收件人被拒绝、ctx 结束以及邮件本身的错误（如 From 地址无效、DKIM 配置错误）直接返回，不计入渠道失败
+type Router struct {
+	cfg   RouterConfig
+	rules map[string][]*providerState
+	all   []*providerState
+}
+
+type providerState struct {
+	Provider
+
+	mu       sync.Mutex
+	failures int
+	until    time.Time
+	tokens   float64
+	last     time.Time
+	day      string
+	sent     int
+}
+
+// NewRouter 创建多渠道发信实例，渠道名称需唯一，规则中引用的渠道必须存在
+func NewRouter(cfg RouterConfig) (*Router, error) {
+	if len(cfg.Providers) == 0 {
+		return nil, errors.New("email: router requires at least one provider")
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultFailureThreshold
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = defaultCooldown
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+
+	r := &Router{cfg: cfg, rules: make(map[string][]*providerState)}
+	byName := make(map[string]*providerState)
+	for _, p := range cfg.Providers {
+		if p.Mail == nil {
+			return nil, fmt.Errorf("email: provider %q has no Mail", p.Name)
+		}
+		if _, ok := byName[p.Name]; ok {
+			return nil, fmt.Errorf("email: duplicate provider %q", p.Name)
+		}
+		if p.Burst <= 0 {
+			p.Burst = int(math.Ceil(p.RatePerSecond))
+		}
+		s := &providerState{Provider: p, tokens: float64(p.Burst)}
+		byName[p.Name] = s
+		r.all = append(r.all, s)
+	}
+	for domain, names := range cfg.Rules {
+		var list []*providerState
+		for _, name := range names {
+			s, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("email: rule for %s references unknown provider %q", domain, name)
+			}
+			list = append(list, s)
+		}
+		r.rules[strings.ToLower(domain)] = list
+	}
+	return r, nil
+}
+
+func (r *Router) SendMail(ctx context.Context, address []string, subject string, body string) error {
+	return r.Send(ctx, &Message{To: address, Subject: subject, HTML: body})
+}
+
+// Send 依次尝试可用的渠道，全部失败时返回各渠道的错误
+// 所有渠道都处于暂停或限流状态时返回 ErrNoProvider 或 ErrRateLimited，二者均为临时错误
+func (r *Router) Send(ctx context.Context, msg *Message) error {
+	rcpts, err := msg.Recipients()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	limited := false
+	for _, p := range r.route(rcpts[0]) {
+		now := time.Now()
+		if !p.healthy(now) {
+			continue
+		}
+		if !p.take(now, r.cfg.Location) {
+			limited = true
+			continue
+		}
+		err := p.Mail.Send(ctx, msg)
+		if err == nil {
+			p.succeed()
+			return nil
+		}
+		if !providerFault(ctx, err) {
+			return err
+		}
+		p.fail(time.Now(), r.cfg.FailureThreshold, r.cfg.Cooldown)
+		errs = append(errs, fmt.Errorf("email: provider %s: %w", p.Name, err))
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if limited {
+		return temporaryError{ErrRateLimited}
+	}
+	return temporaryError{ErrNoProvider}
+}
+
+// Status 各渠道的当前状态，按优先级排列
+func (r *Router) Status() []ProviderStatus {
+	now := time.Now()
+	res := make([]ProviderStatus, len(r.all))
+	for i, p := range r.all {
+		p.mu.Lock()
+		res[i] = ProviderStatus{Name: p.Name, Healthy: !now.Before(p.until), Failures: p.failures}
+		if !res[i].Healthy {
+			res[i].Until = p.until
+		}
+		if p.day == dayKey(now, r.cfg.Location) {
+			res[i].SentToday = p.sent
+		}
+		p.mu.Unlock()
+	}
+	return res
+}
+
+// route 按收件人域名匹配规则，从完整域名开始逐级向上查找，未匹配时使用全部渠道
+// 邮件有多个收件人时以第一个收件人为准
+func (r *Router) route(rcpt string) []*providerState {
+	domain := strings.ToLower(rcpt[strings.LastIndexByte(rcpt, '@')+1:])
+	for {
+		if list, ok := r.rules[domain]; ok {
+			return list
+		}
+		i := strings.IndexByte(domain, '.')
+		if i < 0 {
+			return r.all
+		}
+		domain = domain[i+1:]
+	}
+}
+
+// providerFault 错误是否由渠道导致，需计入失败并切换渠道
+// 只有 SMTP 会话中的错误才归咎于渠道，发信前由调用方导致的错误换渠道也会失败
+func providerFault(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrRecipientRejected) {
+		return false
+	}
+	var se *SendError
+	return errors.As(err, &se)
+}
+
+func (p *providerState) healthy(now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return !now.Before(p.until)
+}
+
+// take 按令牌桶与每日配额占用一次发送额度
+func (p *providerState) take(now time.Time, loc *time.Location) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.DailyQuota > 0 {
+		if day := dayKey(now, loc); day != p.day {
+			p.day, p.sent = day, 0
+		}
+		if p.sent >= p.DailyQuota {
+			return false
+		}
+	}
+	if p.RatePerSecond > 0 {
+		if !p.last.IsZero() {
+			p.tokens += now.Sub(p.last).Seconds() * p.RatePerSecond
+			if burst := float64(p.Burst); p.tokens > burst {
+				p.tokens = burst
+			}
+		}
+		p.last = now
+		if p.tokens < 1 {
+			return false
+		}
+		p.tokens--
+	}
+	if p.DailyQuota > 0 {
+		p.sent++
+	}
+	return true
+}
+
+func (p *providerState) succeed() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures = 0
+	p.until = time.Time{}
+}
+
+// fail 记录一次失败，连续失败达到阈值时暂停；暂停结束后再次失败会立即重新暂停
+func (p *providerState) fail(now time.Time, threshold int, cooldown time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures++
+	if p.failures >= threshold {
+		p.until = now.Add(cooldown)
+	}
+}
+
+func dayKey(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("2006-01-02")
+}
+
+// temporaryError 可稍后重试的错误，Queue 会按临时错误重试
+type temporaryError struct {
+	error
+}
+
+func (e temporaryError) Temporary() bool { return true }
+
+func (e temporaryError) Unwrap() error { return e.error }
@@ -0,0 +1,181 @@
+package email_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/supernarsi/gotool/email"
+	"github.com/supernarsi/gotool/email/emailtest"
+)
+
+var errRelayDown = &email.SendError{Op: "dial", Err: errors.New("connection refused")}
+
+func sendTo(t *testing.T, m email.Mail, to string) error {
+	t.Helper()
+	return m.SendMail(context.Background(), []string{to}, "subject", "body")
+}
+
+func TestRouterFailover(t *testing.T) {
+	primary, backup := emailtest.NewRecorder(), emailtest.NewRecorder()
+	router, err := email.NewRouter(email.RouterConfig{
+		Providers:        []email.Provider{{Name: "primary", Mail: primary}, {Name: "backup", Mail: backup}},
+		FailureThreshold: 2,
+		Cooldown:         100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = sendTo(t, router, "a@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	primary.AssertCount(t, 1)
+
+	// 主渠道故障时切换到备用渠道，连续失败 2 次后暂停使用
+	primary.FailWith(errRelayDown)
+	for i := 0; i < 3; i++ {
+		if err = sendTo(t, router, "a@example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backup.AssertCount(t, 3)
+	status := router.Status()
+	if status[0].Healthy || status[0].Failures != 2 || !status[1].Healthy {
+		t.Errorf("got status %+v", status)
+	}
+
+	// 暂停结束后主渠道恢复
+	primary.FailWith(nil)
+	time.Sleep(120 * time.Millisecond)
+	if err = sendTo(t, router, "a@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	primary.AssertCount(t, 2)
+	if status = router.Status(); !status[0].Healthy || status[0].Failures != 0 {
+		t.Errorf("got status %+v", status)
+	}
+}
+
+func TestRouterAllFailed(t *testing.T) {
+	a, b := emailtest.NewRecorder(), emailtest.NewRecorder()
+	a.FailWith(errRelayDown)
+	b.FailWith(&email.SendError{Op: "auth", Code: 535, Err: errors.New("bad credentials")})
+	router, err := email.NewRouter(email.RouterConfig{
+		Providers:        []email.Provider{{Name: "a", Mail: a}, {Name: "b", Mail: b}},
+		FailureThreshold: 1,
+		Cooldown:         time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sendTo(t, router, "a@example.com")
+	if !errors.Is(err, email.ErrConnection) || !errors.Is(err, email.ErrAuth) {
+		t.Errorf("got err %v, want both provider errors", err)
+	}
+	// 两个渠道都已暂停
+	err = sendTo(t, router, "a@example.com")
+	if !errors.Is(err, email.ErrNoProvider) {
+		t.Errorf("got err %v, want %v", err, email.ErrNoProvider)
+	}
+	var tmp interface{ Temporary() bool }
+	if !errors.As(err, &tmp) || !tmp.Temporary() {
+		t.Error("ErrNoProvider should be temporary")
+	}
+}
+
+func TestRouterNoFailover(t *testing.T) {
+	a, b := emailtest.NewRecorder(), emailtest.NewRecorder()
+	a.FailWith(&email.RecipientError{Rejected: []email.RejectedRecipient{{Address: "a@example.com", Code: 550}}})
+	router, _ := email.NewRouter(email.RouterConfig{
+		Providers: []email.Provider{{Name: "a", Mail: a}, {Name: "b", Mail: b}},
+	})
+
+	if err := sendTo(t, router, "a@example.com"); !errors.Is(err, email.ErrRecipientRejected) {
+		t.Errorf("got err %v, want %v", err, email.ErrRecipientRejected)
+	}
+	b.AssertCount(t, 0)
+	if status := router.Status(); status[0].Failures != 0 {
+		t.Errorf("recipient rejection should not count as provider failure, got %+v", status[0])
+	}
+	if err := router.Send(context.Background(), &email.Message{}); !errors.Is(err, email.ErrNoRecipient) {
+		t.Errorf("got err %v, want %v", err, email.ErrNoRecipient)
+	}
+
+	// 邮件本身的错误不是渠道故障
+	errBadFrom := errors.New("email: invalid From address")
+	a.FailWith(errBadFrom)
+	for i := 0; i < 5; i++ {
+		if err := sendTo(t, router, "a@example.com"); !errors.Is(err, errBadFrom) {
+			t.Errorf("got err %v, want %v", err, errBadFrom)
+		}
+	}
+	b.AssertCount(t, 0)
+	if status := router.Status(); !status[0].Healthy || status[0].Failures != 0 {
+		t.Errorf("caller errors should not count as provider failure, got %+v", status[0])
+	}
+}
+
+func TestRouterRateLimit(t *testing.T) {
+	a, b := emailtest.NewRecorder(), emailtest.NewRecorder()
+	router, err := email.NewRouter(email.RouterConfig{
+		Providers: []email.Provider{
+			{Name: "a", Mail: a, RatePerSecond: 1},
+			{Name: "b", Mail: b, DailyQuota: 2},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err = sendTo(t, router, "a@example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.AssertCount(t, 1)
+	b.AssertCount(t, 2)
+	if err = sendTo(t, router, "a@example.com"); !errors.Is(err, email.ErrRateLimited) {
+		t.Errorf("got err %v, want %v", err, email.ErrRateLimited)
+	}
+	if got := router.Status()[1].SentToday; got != 2 {
+		t.Errorf("got sent today %v, want %v", got, 2)
+	}
+}
+
+func TestRouterRules(t *testing.T) {
+	a, b := emailtest.NewRecorder(), emailtest.NewRecorder()
+	router, err := email.NewRouter(email.RouterConfig{
+		Providers: []email.Provider{{Name: "a", Mail: a}, {Name: "b", Mail: b}},
+		Rules:     map[string][]string{"qq.com": {"b"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, to := range []string{"x@qq.com", "x@vip.QQ.com", "x@example.com"} {
+		if err = sendTo(t, router, to); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.AssertSentTo(t, "x@qq.com")
+	b.AssertSentTo(t, "x@vip.qq.com")
+	a.AssertSentTo(t, "x@example.com")
+	a.AssertCount(t, 1)
+
+	// 规则中的渠道不可用时不回退到其他渠道
+	b.FailWith(errRelayDown)
+	if err = sendTo(t, router, "x@qq.com"); !errors.Is(err, email.ErrConnection) {
+		t.Errorf("got err %v, want %v", err, email.ErrConnection)
+	}
+	a.AssertCount(t, 1)
+
+	if _, err = email.NewRouter(email.RouterConfig{
+		Providers: []email.Provider{{Name: "a", Mail: a}},
+		Rules:     map[string][]string{"qq.com": {"missing"}},
+	}); err == nil {
+		t.Error("unknown provider in rule should fail")
+	}
+}
@@ -0,0 +1,180 @@
+package email_test
+
+import (
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/supernarsi/gotool/email"
+)
+
+// fakeServer 仅用于测试的最小 SMTP 服务端
+type fakeServer struct {
+	ln       net.Listener
+	password string         // 非空时声明 AUTH PLAIN 并校验密码
+	reject   map[string]int // 被拒绝的收件人及响应码
+	silent   bool           // 接受连接后不发送问候，用于测试超时
+	perConn  int            // 大于 0 时，单个连接投递该数量的邮件后对 MAIL 返回 421 并断开
+	dropData bool           // 对 DATA 回复 250 后立即断开，不等待 QUIT
+	failRset bool           // 对每个连接的第一个 RSET 返回 421
+
+	mu        sync.Mutex
+	cmds      []string
+	messages  []string
+	conns     int
+	active    int
+	maxActive int
+}
+
+func newFakeServer(t *testing.T, setup func(s *fakeServer)) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{ln: ln}
+	if setup != nil {
+		setup(s)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { _ = ln.Close() })
+	return s
+}
+
+func (s *fakeServer) config() email.MailerConfig {
+	return email.MailerConfig{
+		Host:        "127.0.0.1",
+		Port:        s.ln.Addr().(*net.TCPAddr).Port,
+		FromAddress: "noreply@example.com",
+		Password:    s.password,
+		TLSMode:     email.TLSNone,
+		DialTimeout: time.Second,
+		SendTimeout: 5 * time.Second,
+	}
+}
+
+// commands 收到的命令动词
+func (s *fakeServer) commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.cmds...)
+}
+
+func (s *fakeServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.messages...)
+}
+
+// stats 累计连接数与最大并发连接数
+func (s *fakeServer) stats() (conns int, maxActive int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns, s.maxActive
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	s.mu.Lock()
+	s.conns++
+	s.active++
+	if s.active > s.maxActive {
+		s.maxActive = s.active
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.active--
+		s.mu.Unlock()
+	}()
+	defer conn.Close()
+	if s.silent {
+		_, _ = conn.Read(make([]byte, 1))
+		return
+	}
+	tc := textproto.NewConn(conn)
+	reply := func(code int, msg string) { _ = tc.PrintfLine("%d %s", code, msg) }
+	reply(220, "fake ESMTP")
+	delivered := 0
+	rsets := 0
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		verb = strings.ToUpper(verb)
+		s.mu.Lock()
+		s.cmds = append(s.cmds, verb)
+		s.mu.Unlock()
+
+		switch verb {
+		case "EHLO", "HELO":
+			if s.password != "" {
+				_ = tc.PrintfLine("250-fake")
+				reply(250, "AUTH PLAIN")
+			} else {
+				reply(250, "fake")
+			}
+		case "AUTH":
+			_, cred, _ := strings.Cut(arg, " ")
+			b, _ := base64.StdEncoding.DecodeString(cred)
+			if parts := strings.Split(string(b), "\x00"); len(parts) == 3 && parts[2] == s.password {
+				reply(235, "2.7.0 authenticated")
+			} else {
+				reply(535, "5.7.8 authentication failed")
+			}
+		case "MAIL":
+			if s.perConn > 0 && delivered >= s.perConn {
+				reply(421, "4.7.0 too many messages, closing connection")
+				return
+			}
+			reply(250, "OK")
+		case "RCPT":
+			addr := strings.Trim(arg[strings.IndexByte(arg, ':')+1:], "<> ")
+			if code, ok := s.reject[addr]; ok {
+				reply(code, "no such user "+strconv.Quote(addr))
+			} else {
+				reply(250, "OK")
+			}
+		case "DATA":
+			reply(354, "go ahead")
+			data, err := tc.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, string(data))
+			s.mu.Unlock()
+			delivered++
+			reply(250, "queued")
+			if s.dropData {
+				return
+			}
+		case "RSET":
+			rsets++
+			if s.failRset && rsets == 1 {
+				reply(421, "4.3.0 temporary failure")
+			} else {
+				reply(250, "OK")
+			}
+		case "QUIT":
+			reply(221, "bye")
+			return
+		default:
+			reply(250, "OK")
+		}
+	}
+}
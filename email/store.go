@@ -0,0 +1,88 @@
+package email
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const storeExt = ".json"
+
+// FileStore 基于目录的队列持久化，每封邮件保存为一个 JSON 文件
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore 在 dir 下保存队列中的邮件，目录不存在时创建
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Save 先写入临时文件再重命名，避免进程中断时留下不完整的文件
+func (s *FileStore) Save(m *QueuedMessage) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path(m.ID))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func (s *FileStore) Delete(id string) error {
+	err := os.Remove(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Load 按入队时间排序返回所有邮件，无法解析的文件会被跳过
+func (s *FileStore) Load() ([]*QueuedMessage, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var res []*QueuedMessage
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), storeExt) || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m := new(QueuedMessage)
+		if err = json.Unmarshal(data, m); err != nil || m.ID == "" {
+			continue
+		}
+		res = append(res, m)
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].EnqueuedAt.Before(res[j].EnqueuedAt) })
+	return res, nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, filepath.Base(id)+storeExt)
+}
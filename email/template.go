@@ -0,0 +1,195 @@
+package email
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+const (
+	defaultTemplateLayout = "layout"
+	defaultTemplateLocale = "en"
+	contentTemplate       = "content"
+)
+
+var ErrTemplateNotFound = errors.New("email: template not found")
+
+// TemplateConfig 邮件模板配置
+type TemplateConfig struct {
+	Layout        string         // 布局模板名，默认 "layout"，对应 layout.html 或 layout.<locale>.html；不存在时不使用布局
+	DefaultLocale string         // 找不到请求的语言时使用的语言，默认 "en"
+	Funcs         map[string]any // 模板函数，同时用于正文、标题与纯文本模板
+}
+
+// Templates 从 fs.FS 加载的邮件模板集合，加载后可并发使用
+//
+// 文件按 <name>[.<locale>].<ext> 命名：
+//   - .html 正文，在布局中以 {{template "content" .}} 引用
+//   - .subject 标题
+//   - .txt 纯文本正文，可选，不存在时由 HTML 自动生成
+//
+// 语言按 zh-CN → zh → DefaultLocale → 无语言后缀 的顺序回退，各文件独立回退
+type Templates struct {
+	cfg      TemplateConfig
+	html     map[string]map[string]*htmltemplate.Template // 正文 key → 布局 key → 模板，不使用布局时布局 key 为空
+	layouts  map[string]struct{}
+	subjects map[string]*texttemplate.Template // key 为 name 与小写的 locale，以 "." 连接，无语言时仅为 name
+	texts    map[string]*texttemplate.Template
+}
+
+// NewTemplates 加载 fsys 根目录下的所有模板，可用 fs.Sub 指定子目录
+func NewTemplates(fsys fs.FS, cfg TemplateConfig) (*Templates, error) {
+	if cfg.Layout == "" {
+		cfg.Layout = defaultTemplateLayout
+	}
+	if cfg.DefaultLocale == "" {
+		cfg.DefaultLocale = defaultTemplateLocale
+	}
+	t := &Templates{
+		cfg:      cfg,
+		html:     make(map[string]map[string]*htmltemplate.Template),
+		layouts:  make(map[string]struct{}),
+		subjects: make(map[string]*texttemplate.Template),
+		texts:    make(map[string]*texttemplate.Template),
+	}
+
+	files, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	bodies := make(map[string]string)
+	layouts := make(map[string]string)
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		ext := path.Ext(f.Name())
+		key := strings.ToLower(strings.TrimSuffix(f.Name(), ext))
+		data, err := fs.ReadFile(fsys, f.Name())
+		if err != nil {
+			return nil, err
+		}
+		switch ext {
+		case ".html":
+			if name, _ := splitTemplateKey(key); name == strings.ToLower(cfg.Layout) {
+				layouts[key] = string(data)
+			} else {
+				bodies[key] = string(data)
+			}
+		case ".subject":
+			tmpl, err := texttemplate.New(f.Name()).Funcs(cfg.Funcs).Parse(string(data))
+			if err != nil {
+				return nil, err
+			}
+			t.subjects[key] = tmpl
+		case ".txt":
+			tmpl, err := texttemplate.New(f.Name()).Funcs(cfg.Funcs).Parse(string(data))
+			if err != nil {
+				return nil, err
+			}
+			t.texts[key] = tmpl
+		}
+	}
+
+	// 布局按 Render 时请求的语言回退，与正文的语言无关，因此为每个正文与布局的组合预先解析
+	for key := range layouts {
+		t.layouts[key] = struct{}{}
+	}
+	for key, body := range bodies {
+		t.html[key] = make(map[string]*htmltemplate.Template, len(layouts)+1)
+		tmpl, err := htmltemplate.New(contentTemplate).Funcs(cfg.Funcs).Parse(body)
+		if err != nil {
+			return nil, fmt.Errorf("email: parse template %s: %w", key, err)
+		}
+		t.html[key][""] = tmpl
+		for layoutKey, layout := range layouts {
+			tmpl, err := htmltemplate.New(layoutKey).Funcs(cfg.Funcs).Parse(layout)
+			if err == nil {
+				_, err = tmpl.New(contentTemplate).Parse(body)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("email: parse template %s with %s: %w", key, layoutKey, err)
+			}
+			t.html[key][layoutKey] = tmpl
+		}
+	}
+	return t, nil
+}
+
+// Render 渲染 name 模板为邮件，返回的 Message 已填充 Subject、HTML 与 Text，收件人等由调用方设置
+func (t *Templates) Render(name string, locale string, data any) (*Message, error) {
+	name = strings.ToLower(name)
+	locale = normalizeLocale(locale)
+	htmlKey, ok := lookupLocale(t.html, name, locale, t.cfg.DefaultLocale)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	layoutKey, _ := lookupLocale(t.layouts, strings.ToLower(t.cfg.Layout), locale, t.cfg.DefaultLocale)
+
+	var buf bytes.Buffer
+	if err := t.html[htmlKey][layoutKey].Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	msg := &Message{HTML: buf.String()}
+
+	if key, ok := lookupLocale(t.subjects, name, locale, t.cfg.DefaultLocale); ok {
+		buf.Reset()
+		if err := t.subjects[key].Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		// 标题为单行，合并换行以防止头部注入
+		msg.Subject = strings.Join(strings.Fields(buf.String()), " ")
+	}
+
+	if key, ok := lookupLocale(t.texts, name, locale, t.cfg.DefaultLocale); ok {
+		buf.Reset()
+		if err := t.texts[key].Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		msg.Text = buf.String()
+	} else {
+		msg.Text = HTMLToText(msg.HTML)
+	}
+	return msg, nil
+}
+
+// splitTemplateKey 将 welcome.zh-cn 拆分为 welcome 与 zh-cn
+func splitTemplateKey(key string) (name string, locale string) {
+	if i := strings.IndexByte(key, '.'); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+	return key, ""
+}
+
+// normalizeLocale 统一为小写并以 - 分隔，如 zh_CN → zh-cn
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// lookupLocale 按 locale 逐级去掉子标签回退，再依次尝试默认语言与无语言后缀的模板
+func lookupLocale[T any](m map[string]T, name string, locale string, defaultLocale string) (string, bool) {
+	var chain []string
+	for _, l := range []string{locale, normalizeLocale(defaultLocale)} {
+		for l != "" {
+			chain = append(chain, name+"."+l)
+			i := strings.LastIndexByte(l, '-')
+			if i < 0 {
+				break
+			}
+			l = l[:i]
+		}
+	}
+	chain = append(chain, name)
+	for _, key := range chain {
+		if _, ok := m[key]; ok {
+			return key, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,139 @@
+package email_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/supernarsi/gotool/email"
+)
+
+func newTemplates(t *testing.T) *email.Templates {
+	t.Helper()
+	fsys := fstest.MapFS{
+		"layout.html":           {Data: []byte(`<html><body>{{template "content" .}}<p>footer</p></body></html>`)},
+		"layout.zh.html":        {Data: []byte(`<html><body>{{template "content" .}}<p>页脚</p></body></html>`)},
+		"welcome.en.html":       {Data: []byte(`<h1>Welcome, {{.Name}}</h1><p>Click <a href="{{.URL}}">here</a>.</p>`)},
+		"welcome.en.subject":    {Data: []byte("Welcome {{.Name}}\n")},
+		"welcome.zh.html":       {Data: []byte(`<h1>欢迎，{{.Name}}</h1>`)},
+		"welcome.zh.subject":    {Data: []byte(`欢迎 {{.Name}}`)},
+		"welcome.zh-TW.subject": {Data: []byte(`歡迎 {{.Name}}`)},
+		"reset.html":            {Data: []byte(`<p>code {{.Code}}</p>`)},
+		"reset.subject":         {Data: []byte(`{{upper "reset"}}`)},
+		"reset.txt":             {Data: []byte(`code: {{.Code}}`)},
+	}
+	tmpl, err := email.NewTemplates(fsys, email.TemplateConfig{
+		Funcs: map[string]any{"upper": strings.ToUpper},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tmpl
+}
+
+func TestTemplatesRender(t *testing.T) {
+	tmpl := newTemplates(t)
+	data := map[string]string{"Name": "<Tom>", "URL": "https://example.com/a?b=1", "Code": "1234"}
+	tests := []struct {
+		name    string
+		tmpl    string
+		locale  string
+		subject string
+		html    []string
+		text    string
+	}{
+		{
+			name: "exact locale", tmpl: "welcome", locale: "en",
+			subject: "Welcome <Tom>",
+			html:    []string{"<h1>Welcome, &lt;Tom&gt;</h1>", "<p>footer</p>", `href="https://example.com/a?b=1"`},
+			text:    "Welcome, <Tom>\n\nClick here (https://example.com/a?b=1).\n\nfooter",
+		},
+		{
+			name: "fallback to language", tmpl: "welcome", locale: "zh_CN",
+			subject: "欢迎 <Tom>",
+			html:    []string{"<h1>欢迎，&lt;Tom&gt;</h1>", "<p>页脚</p>"},
+			text:    "欢迎，<Tom>\n\n页脚",
+		},
+		{
+			name: "region subject", tmpl: "welcome", locale: "zh-TW",
+			subject: "歡迎 <Tom>",
+			html:    []string{"<h1>欢迎，&lt;Tom&gt;</h1>"},
+		},
+		{
+			name: "fallback to default", tmpl: "welcome", locale: "fr-FR",
+			subject: "Welcome <Tom>",
+			html:    []string{"<h1>Welcome, &lt;Tom&gt;</h1>"},
+		},
+		{
+			name: "no locale with text template", tmpl: "reset", locale: "zh",
+			subject: "RESET",
+			html:    []string{"<p>code 1234</p>", "<p>页脚</p>"},
+			text:    "code: 1234",
+		},
+		{
+			name: "layout follows requested locale", tmpl: "reset", locale: "zh-CN",
+			subject: "RESET",
+			html:    []string{"<p>code 1234</p>", "<p>页脚</p>"},
+		},
+		{
+			name: "layout falls back to default", tmpl: "reset", locale: "fr",
+			subject: "RESET",
+			html:    []string{"<p>code 1234</p>", "<p>footer</p>"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := tmpl.Render(tt.tmpl, tt.locale, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Subject != tt.subject {
+				t.Errorf("got subject %q, want %q", msg.Subject, tt.subject)
+			}
+			for _, want := range tt.html {
+				if !strings.Contains(msg.HTML, want) {
+					t.Errorf("got html %v, want contains %v", msg.HTML, want)
+				}
+			}
+			if tt.text != "" && msg.Text != tt.text {
+				t.Errorf("got text %q, want %q", msg.Text, tt.text)
+			}
+		})
+	}
+
+	if _, err := tmpl.Render("missing", "en", nil); !errors.Is(err, email.ErrTemplateNotFound) {
+		t.Errorf("got err %v, want %v", err, email.ErrTemplateNotFound)
+	}
+}
+
+func TestTemplatesParseError(t *testing.T) {
+	fsys := fstest.MapFS{"bad.html": {Data: []byte(`{{.Name`)}}
+	if _, err := email.NewTemplates(fsys, email.TemplateConfig{}); err == nil {
+		t.Error("want parse error")
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"inline", "Hello <b>world</b> <i>again</i>!", "Hello world again!"},
+		{"paragraphs", "<p>one</p>\n  <p>two<br>three</p>", "one\n\ntwo\nthree"},
+		{"list", "<ul><li>a</li><li>b</li></ul>", "- a\n- b"},
+		{"link", `<a href="https://x.io">site</a> <a href="#top">top</a>`, "site (https://x.io) top"},
+		{"same link text", `<a href="https://x.io">https://x.io</a>`, "https://x.io"},
+		{"skip style", "<html><head><title>t</title><style>p{}</style></head><body>text</body></html>", "text"},
+		{"entities", "a &amp; b &lt;c&gt;", "a & b <c>"},
+		{"image alt", `<img src="x.png" alt="logo"> hi`, "logo hi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := email.HTMLToText(tt.input); got != tt.want {
+				t.Errorf("got result %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
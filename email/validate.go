@@ -0,0 +1,207 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// RFC 5321 4.5.3.1 的长度限制
+const (
+	maxLocalLength   = 64
+	maxDomainLength  = 255
+	maxLabelLength   = 63
+	maxAddressLength = 254 // 路径最长 256 个字符，去掉两侧尖括号
+)
+
+var (
+	ErrInvalidAddress   = errors.New("email: invalid address")
+	ErrDisposableDomain = errors.New("email: disposable domain")
+	ErrNoMX             = errors.New("email: domain does not accept mail")
+)
+
+// MXResolver 查询 MX 记录，*net.Resolver 满足该接口
+type MXResolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// HostResolver 查询域名的 A/AAAA 记录，*net.Resolver 满足该接口
+// Validator.Resolver 同时实现该接口时，没有 MX 记录的域名按 RFC 5321 §5.1 的隐式 MX 回退检查地址记录
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Validator 邮箱地址校验与规范化，零值为严格模式：仅接受不含显示名的纯地址，域名至少包含两级
+type Validator struct {
+	AllowDisplayName       bool                     // 允许 "Bob <bob@example.com>" 形式
+	AllowSingleLabelDomain bool                     // 允许 user@localhost 这类不含点的域名
+	AllowUTF8Local         bool                     // 允许本地部分包含非 ASCII 字符（需服务端支持 SMTPUTF8）
+	IsDisposable           func(domain string) bool // 一次性邮箱域名判断，参数为小写的 ASCII 域名
+	Resolver               MXResolver               // 非 nil 时 Validate 检查域名的 MX 记录
+	RequireMX              bool                     // 要求域名有 MX 记录，不回退到 A/AAAA 记录
+
+	StripPlusTag bool // 规范化时去掉所有域名下本地部分的 +tag
+	GmailRules   bool // 规范化时对 gmail.com 与 googlemail.com 去掉本地部分的点与 +tag，并统一为 gmail.com
+}
+
+// Validate 校验地址的语法、长度与域名，配置了 IsDisposable 与 Resolver 时同时检查
+func (v *Validator) Validate(ctx context.Context, address string) error {
+	_, domain, err := v.parse(address)
+	if err != nil {
+		return err
+	}
+	if v.IsDisposable != nil && v.IsDisposable(domain) {
+		return fmt.Errorf("%w: %s", ErrDisposableDomain, domain)
+	}
+	if v.Resolver != nil {
+		return checkMX(ctx, v.Resolver, domain, v.RequireMX)
+	}
+	return nil
+}
+
+// Normalize 校验语法后返回用于去重的规范形式：域名转为小写的 ASCII（punycode），
+// 本地部分按 StripPlusTag 与 GmailRules 处理，其余情况保留原样（本地部分区分大小写）
+func (v *Validator) Normalize(address string) (string, error) {
+	local, domain, err := v.parse(address)
+	if err != nil {
+		return "", err
+	}
+	if v.GmailRules && (domain == "gmail.com" || domain == "googlemail.com") {
+		local = strings.ReplaceAll(stripPlusTag(strings.ToLower(local)), ".", "")
+		domain = "gmail.com"
+	} else if v.StripPlusTag {
+		local = stripPlusTag(local)
+	}
+	return local + "@" + domain, nil
+}
+
+// parse 拆分并校验地址，返回本地部分与小写的 ASCII 域名
+func (v *Validator) parse(address string) (local string, domain string, err error) {
+	address = strings.TrimSpace(address)
+	addr, err := mail.ParseAddress(address)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidAddress, err)
+	}
+	if !v.AllowDisplayName && addr.Address != address {
+		// 显示名、尖括号与带引号的本地部分都会使解析结果与输入不同
+		return "", "", fmt.Errorf("%w: %q is not a bare address", ErrInvalidAddress, address)
+	}
+
+	i := strings.LastIndexByte(addr.Address, '@')
+	local, domain = addr.Address[:i], addr.Address[i+1:]
+	if len(local) > maxLocalLength {
+		return "", "", fmt.Errorf("%w: local part exceeds %d octets", ErrInvalidAddress, maxLocalLength)
+	}
+	if !v.AllowUTF8Local && !isASCII(local) {
+		return "", "", fmt.Errorf("%w: non-ASCII local part", ErrInvalidAddress)
+	}
+
+	domain, err = idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidAddress, err)
+	}
+	if err = v.checkDomain(domain); err != nil {
+		return "", "", err
+	}
+	if len(local)+1+len(domain) > maxAddressLength {
+		return "", "", fmt.Errorf("%w: address exceeds %d octets", ErrInvalidAddress, maxAddressLength)
+	}
+	return local, domain, nil
+}
+
+func (v *Validator) checkDomain(domain string) error {
+	if domain == "" || len(domain) > maxDomainLength {
+		return fmt.Errorf("%w: invalid domain length", ErrInvalidAddress)
+	}
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 && !v.AllowSingleLabelDomain {
+		return fmt.Errorf("%w: domain %s has no dot", ErrInvalidAddress, domain)
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > maxLabelLength {
+			return fmt.Errorf("%w: invalid domain label in %s", ErrInvalidAddress, domain)
+		}
+	}
+	if tld := labels[len(labels)-1]; strings.Trim(tld, "0123456789") == "" {
+		return fmt.Errorf("%w: numeric top-level domain %s", ErrInvalidAddress, domain)
+	}
+	return nil
+}
+
+// checkMX 域名需有 MX 记录且不是 RFC 7505 的 Null MX
+// 没有 MX 记录时，未要求 MX 且 r 实现了 HostResolver 则以域名自身的地址记录作为隐式 MX
+func checkMX(ctx context.Context, r MXResolver, domain string, requireMX bool) error {
+	records, err := r.LookupMX(ctx, domain)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	if len(records) > 0 {
+		for _, mx := range records {
+			if host := strings.TrimSuffix(mx.Host, "."); host != "" {
+				return nil
+			}
+		}
+		// 只有 Null MX，明确声明不接收邮件
+		return fmt.Errorf("%w: %s", ErrNoMX, domain)
+	}
+
+	hr, ok := r.(HostResolver)
+	if requireMX || !ok {
+		return fmt.Errorf("%w: %s", ErrNoMX, domain)
+	}
+	addrs, err := hr.LookupHost(ctx, domain)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("%w: %s", ErrNoMX, domain)
+	}
+	return nil
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// DisposableDomains 返回判断一次性邮箱域名的函数，列表中的域名及其子域名都会命中
+func DisposableDomains(domains ...string) func(domain string) bool {
+	set := make(map[string]struct{}, len(domains))
+	for _, d := range domains {
+		set[strings.ToLower(strings.TrimSuffix(d, "."))] = struct{}{}
+	}
+	return func(domain string) bool {
+		for {
+			if _, ok := set[domain]; ok {
+				return true
+			}
+			i := strings.IndexByte(domain, '.')
+			if i < 0 {
+				return false
+			}
+			domain = domain[i+1:]
+		}
+	}
+}
+
+func stripPlusTag(local string) string {
+	if i := strings.IndexByte(local, '+'); i > 0 {
+		return local[:i]
+	}
+	return local
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
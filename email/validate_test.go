@@ -0,0 +1,163 @@
+package email_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/supernarsi/gotool/email"
+)
+
+// fakeResolver 按域名返回固定的 MX 记录，未配置的域名返回 NXDOMAIN
+type fakeResolver map[string][]*net.MX
+
+func (r fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	records, ok := r[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+// fakeHostResolver 在 fakeResolver 之外按域名返回固定的地址记录
+type fakeHostResolver struct {
+	fakeResolver
+	hosts map[string][]string
+}
+
+func (r fakeHostResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs, ok := r.hosts[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+func TestValidatorValidate(t *testing.T) {
+	strict := &email.Validator{}
+	lenient := &email.Validator{AllowDisplayName: true, AllowSingleLabelDomain: true}
+	tests := []struct {
+		name  string
+		v     *email.Validator
+		input string
+		want  error
+	}{
+		{"bare", strict, "bob@example.com", nil},
+		{"plus and dots", strict, "bob.smith+news@mail.example.co.uk", nil},
+		{"display name strict", strict, "Bob <bob@example.com>", email.ErrInvalidAddress},
+		{"angle brackets strict", strict, "<bob@example.com>", email.ErrInvalidAddress},
+		{"display name lenient", lenient, "Bob <bob@example.com>", nil},
+		{"single label strict", strict, "bob@localhost", email.ErrInvalidAddress},
+		{"single label lenient", lenient, "bob@localhost", nil},
+		{"numeric tld", strict, "bob@1.2.3.4", email.ErrInvalidAddress},
+		{"hyphen label", strict, "bob@-example.com", email.ErrInvalidAddress},
+		{"local too long", strict, strings.Repeat("a", 65) + "@example.com", email.ErrInvalidAddress},
+		{"local max", strict, strings.Repeat("a", 64) + "@example.com", nil},
+		{"label too long", strict, "bob@" + strings.Repeat("a", 64) + ".com", email.ErrInvalidAddress},
+		{"address too long", strict, strings.Repeat("a", 64) + "@" + strings.Repeat(strings.Repeat("b", 60)+".", 4) + "com", email.ErrInvalidAddress},
+		{"idn domain", strict, "user@例子.中国", nil},
+		{"utf8 local strict", strict, "用户@example.com", email.ErrInvalidAddress},
+		{"utf8 local allowed", &email.Validator{AllowUTF8Local: true}, "用户@example.com", nil},
+		{"no at", strict, "bob.example.com", email.ErrInvalidAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.v.Validate(context.Background(), tt.input); !errors.Is(err, tt.want) {
+				t.Errorf("got err %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatorDomainChecks(t *testing.T) {
+	v := &email.Validator{
+		IsDisposable: email.DisposableDomains("mailinator.com", "Temp-Mail.org"),
+		Resolver: fakeResolver{
+			"example.com":            {{Host: "mx.example.com.", Pref: 10}},
+			"nullmx.com":             {{Host: ".", Pref: 0}},
+			"xn--fsqu00a.xn--fiqs8s": {{Host: "mx.example.cn.", Pref: 10}},
+		},
+	}
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{"has mx", "bob@example.com", nil},
+		{"idn mx", "user@例子.中国", nil},
+		{"null mx", "bob@nullmx.com", email.ErrNoMX},
+		{"nxdomain", "bob@nowhere.test", email.ErrNoMX},
+		{"disposable", "bob@mailinator.com", email.ErrDisposableDomain},
+		{"disposable subdomain", "bob@x.temp-mail.org", email.ErrDisposableDomain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := v.Validate(context.Background(), tt.input); !errors.Is(err, tt.want) {
+				t.Errorf("got err %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatorNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		v     *email.Validator
+		input string
+		want  string
+	}{
+		{"lowercase domain", &email.Validator{}, "Bob@Example.COM", "Bob@example.com"},
+		{"punycode", &email.Validator{}, "user@例子.中国", "user@xn--fsqu00a.xn--fiqs8s"},
+		{"keep plus", &email.Validator{}, "bob+news@example.com", "bob+news@example.com"},
+		{"strip plus", &email.Validator{StripPlusTag: true}, "bob+news@example.com", "bob@example.com"},
+		{"gmail", &email.Validator{GmailRules: true}, "Bob.Smith+news@GoogleMail.com", "bobsmith@gmail.com"},
+		{"gmail rules other domain", &email.Validator{GmailRules: true}, "bob.smith+news@example.com", "bob.smith+news@example.com"},
+		{"display name", &email.Validator{AllowDisplayName: true}, "Bob <bob@Example.com>", "bob@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.v.Normalize(tt.input)
+			if err != nil || got != tt.want {
+				t.Errorf("got result %v, err %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatorImplicitMX(t *testing.T) {
+	resolver := fakeHostResolver{
+		fakeResolver: fakeResolver{
+			"example.com": {{Host: "mx.example.com.", Pref: 10}},
+			"nullmx.com":  {{Host: ".", Pref: 0}},
+			"nomx.com":    {},
+		},
+		hosts: map[string][]string{
+			"nomx.com":   {"192.0.2.1"},
+			"a-only.com": {"2001:db8::1"},
+			"nullmx.com": {"192.0.2.2"},
+		},
+	}
+	tests := []struct {
+		name  string
+		v     *email.Validator
+		input string
+		want  error
+	}{
+		{"has mx", &email.Validator{Resolver: resolver}, "bob@example.com", nil},
+		{"empty mx with address", &email.Validator{Resolver: resolver}, "bob@nomx.com", nil},
+		{"no mx with address", &email.Validator{Resolver: resolver}, "bob@a-only.com", nil},
+		{"null mx with address", &email.Validator{Resolver: resolver}, "bob@nullmx.com", email.ErrNoMX},
+		{"nxdomain", &email.Validator{Resolver: resolver}, "bob@nowhere.test", email.ErrNoMX},
+		{"require mx", &email.Validator{Resolver: resolver, RequireMX: true}, "bob@a-only.com", email.ErrNoMX},
+		{"mx only resolver", &email.Validator{Resolver: resolver.fakeResolver}, "bob@nomx.com", email.ErrNoMX},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.v.Validate(context.Background(), tt.input); !errors.Is(err, tt.want) {
+				t.Errorf("got err %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
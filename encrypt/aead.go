@@ -0,0 +1,99 @@
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// AEADKeyLen AES-256-GCM 与 XChaCha20-Poly1305 的密钥长度
+const AEADKeyLen = 32
+
+// Algorithm 认证加密算法，数值会写入密文信封，不可更改
+type Algorithm byte
+
+const (
+	AES256GCM         Algorithm = 1 // 12 字节随机 nonce，同一密钥加密的消息数应控制在 2^32 以内
+	XChaCha20Poly1305 Algorithm = 2 // 24 字节随机 nonce，同一密钥可加密的消息数基本不受限制
+)
+
+var (
+	ErrInvalidKey        = errors.New("invalid key, must be 32 bytes")
+	ErrUnknownAlgorithm  = errors.New("unknown encryption algorithm")
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+	ErrDecrypt           = errors.New("message authentication failed")
+)
+
+// String 算法名称
+func (alg Algorithm) String() string {
+	switch alg {
+	case AES256GCM:
+		return "AES-256-GCM"
+	case XChaCha20Poly1305:
+		return "XChaCha20-Poly1305"
+	}
+	return "unknown"
+}
+
+// newAEAD 按算法创建 AEAD 实例
+func newAEAD(alg Algorithm, key []byte) (cipher.AEAD, error) {
+	if len(key) != AEADKeyLen {
+		return nil, ErrInvalidKey
+	}
+	switch alg {
+	case AES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case XChaCha20Poly1305:
+		return chacha20poly1305.NewX(key)
+	}
+	return nil, ErrUnknownAlgorithm
+}
+
+// Seal 使用 32 字节密钥加密，返回 nonce || 密文 || tag
+// additionalData 不会被加密，但解密时必须提供相同的值，可用于绑定用户 ID、字段名等上下文
+func Seal(alg Algorithm, key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(alg, key)
+	if err != nil {
+		return nil, err
+	}
+	return seal(aead, nil, plaintext, additionalData)
+}
+
+// Open 解密 Seal 的结果，密钥、算法或 additionalData 不匹配以及密文被篡改时返回 ErrDecrypt
+func Open(alg Algorithm, key, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(alg, key)
+	if err != nil {
+		return nil, err
+	}
+	return open(aead, ciphertext, additionalData)
+}
+
+// seal 生成随机 nonce 并追加 nonce || 密文 || tag 到 dst
+func seal(aead cipher.AEAD, dst, plaintext, additionalData []byte) ([]byte, error) {
+	n := len(dst)
+	dst = append(dst, make([]byte, aead.NonceSize())...)
+	nonce := dst[n:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(dst, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrInvalidCiphertext
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,63 @@
+package encrypt_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/supernarsi/gotool/encrypt"
+)
+
+var (
+	testAEADKey  = bytes.Repeat([]byte{0x42}, encrypt.AEADKeyLen)
+	testAEADKey2 = bytes.Repeat([]byte{0x24}, encrypt.AEADKeyLen)
+)
+
+func TestSealOpen(t *testing.T) {
+	for _, alg := range []encrypt.Algorithm{encrypt.AES256GCM, encrypt.XChaCha20Poly1305} {
+		t.Run(alg.String(), func(t *testing.T) {
+			plaintext, ad := []byte("13800138000"), []byte("user:1")
+			c1, err := encrypt.Seal(alg, testAEADKey, plaintext, ad)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c2, _ := encrypt.Seal(alg, testAEADKey, plaintext, ad)
+			if bytes.Equal(c1, c2) {
+				t.Error("ciphertexts of the same plaintext should differ")
+			}
+			got, err := encrypt.Open(alg, testAEADKey, c1, ad)
+			if err != nil || !bytes.Equal(got, plaintext) {
+				t.Errorf("got result %s %v, want %s", got, err, plaintext)
+			}
+
+			tampered := append([]byte(nil), c1...)
+			tampered[len(tampered)-1] ^= 1
+			tests := []struct {
+				name string
+				key  []byte
+				c    []byte
+				ad   []byte
+				err  error
+			}{
+				{"wrong key", testAEADKey2, c1, ad, encrypt.ErrDecrypt},
+				{"wrong ad", testAEADKey, c1, []byte("user:2"), encrypt.ErrDecrypt},
+				{"tampered", testAEADKey, tampered, ad, encrypt.ErrDecrypt},
+				{"truncated", testAEADKey, c1[:10], ad, encrypt.ErrInvalidCiphertext},
+				{"short key", testAEADKey[:16], c1, ad, encrypt.ErrInvalidKey},
+			}
+			for _, tt := range tests {
+				if _, err := encrypt.Open(alg, tt.key, tt.c, tt.ad); !errors.Is(err, tt.err) {
+					t.Errorf("%s: got err %v, want %v", tt.name, err, tt.err)
+				}
+			}
+		})
+	}
+
+	if _, err := encrypt.Seal(encrypt.Algorithm(9), testAEADKey, nil, nil); !errors.Is(err, encrypt.ErrUnknownAlgorithm) {
+		t.Errorf("got err %v, want %v", err, encrypt.ErrUnknownAlgorithm)
+	}
+	c, _ := encrypt.Seal(encrypt.AES256GCM, testAEADKey, []byte("longer than the nonce size difference"), nil)
+	if _, err := encrypt.Open(encrypt.XChaCha20Poly1305, testAEADKey, c, nil); !errors.Is(err, encrypt.ErrDecrypt) {
+		t.Errorf("got err %v, want %v", err, encrypt.ErrDecrypt)
+	}
+}
@@ -0,0 +1,101 @@
+package encrypt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+const minBlindIndexKeyLen = 32
+
+var (
+	ErrBlindIndexKey  = errors.New("blind index key must be at least 32 bytes")
+	ErrBlindIndexBits = errors.New("blind index bits must be between 1 and 256")
+)
+
+// BlindIndexConfig 盲索引配置
+type BlindIndexConfig struct {
+	Key       []byte              // HMAC-SHA256 密钥，至少 32 字节，不要与加密密钥共用
+	Name      string              // 字段名，同一密钥用于多个字段时使各字段的索引互不相同
+	Normalize func(string) string // 计算前的规范化，如 NormalizeEmail，nil 表示不处理
+	Bits      int                 // 截断到的位数，默认 256；越短泄露越少，但误匹配越多，查询后需解密比对
+}
+
+// BlindIndex 基于 HMAC 的盲索引，与密文一起存储，查询时用相同配置计算索引后等值匹配
+type BlindIndex struct {
+	cfg BlindIndexConfig
+}
+
+// NewBlindIndex 创建盲索引生成器
+func NewBlindIndex(cfg BlindIndexConfig) (*BlindIndex, error) {
+	if len(cfg.Key) < minBlindIndexKeyLen {
+		return nil, ErrBlindIndexKey
+	}
+	if cfg.Bits == 0 {
+		cfg.Bits = sha256.Size * 8
+	}
+	if cfg.Bits < 0 || cfg.Bits > sha256.Size*8 {
+		return nil, ErrBlindIndexBits
+	}
+	cfg.Key = append([]byte(nil), cfg.Key...)
+	return &BlindIndex{cfg: cfg}, nil
+}
+
+// Index 计算索引，长度为 Bits 向上取整到字节，多余的低位置零
+func (b *BlindIndex) Index(value string) []byte {
+	if b.cfg.Normalize != nil {
+		value = b.cfg.Normalize(value)
+	}
+	mac := hmac.New(sha256.New, b.cfg.Key)
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(len(b.cfg.Name)))
+	mac.Write(n[:])
+	mac.Write([]byte(b.cfg.Name))
+	mac.Write([]byte(value))
+	sum := mac.Sum(nil)
+
+	sum = sum[:(b.cfg.Bits+7)/8]
+	if r := b.cfg.Bits % 8; r > 0 {
+		sum[len(sum)-1] &= byte(0xff) << (8 - r)
+	}
+	return sum
+}
+
+// IndexHex 以 hex 字符串返回索引，便于存入文本字段
+func (b *BlindIndex) IndexHex(value string) string {
+	return hex.EncodeToString(b.Index(value))
+}
+
+// NormalizeEmail 去掉首尾空白并转为小写
+func NormalizeEmail(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// NormalizePhone 只保留数字，开头的 + 保留，用于忽略空格、短横线与括号等格式差异
+func NormalizePhone(s string) string {
+	s = strings.TrimSpace(s)
+	var b strings.Builder
+	if strings.HasPrefix(s, "+") {
+		b.WriteByte('+')
+	}
+	for _, r := range s {
+		if r <= unicode.MaxASCII && unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ChainNormalizers 依次执行多个规范化函数
+func ChainNormalizers(fns ...func(string) string) func(string) string {
+	return func(s string) string {
+		for _, fn := range fns {
+			s = fn(s)
+		}
+		return s
+	}
+}
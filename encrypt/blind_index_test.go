@@ -0,0 +1,85 @@
+package encrypt_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/supernarsi/gotool/encrypt"
+)
+
+var testIndexKey = bytes.Repeat([]byte{0x5a}, 32)
+
+func TestBlindIndex(t *testing.T) {
+	email, err := encrypt.NewBlindIndex(encrypt.BlindIndexConfig{Key: testIndexKey, Name: "users.email", Normalize: encrypt.NormalizeEmail})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := email.IndexHex(" Alice@Example.com "); got != email.IndexHex("alice@example.com") || len(got) != 64 {
+		t.Errorf("got result %v, want normalized 32 bytes index", got)
+	}
+	if email.IndexHex("alice@example.com") == email.IndexHex("bob@example.com") {
+		t.Error("different values should have different indexes")
+	}
+
+	// 不同字段使用同一密钥时索引不同
+	other, _ := encrypt.NewBlindIndex(encrypt.BlindIndexConfig{Key: testIndexKey, Name: "users.backup_email", Normalize: encrypt.NormalizeEmail})
+	if email.IndexHex("alice@example.com") == other.IndexHex("alice@example.com") {
+		t.Error("different names should have different indexes")
+	}
+}
+
+func TestBlindIndexBits(t *testing.T) {
+	full, _ := encrypt.NewBlindIndex(encrypt.BlindIndexConfig{Key: testIndexKey})
+	tests := []struct {
+		bits int
+		size int
+	}{
+		{8, 1},
+		{12, 2},
+		{16, 2},
+		{255, 32},
+	}
+	for _, tt := range tests {
+		b, err := encrypt.NewBlindIndex(encrypt.BlindIndexConfig{Key: testIndexKey, Bits: tt.bits})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, want := b.Index("13800138000"), full.Index("13800138000")
+		if len(got) != tt.size || !bytes.Equal(got[:len(got)-1], want[:len(got)-1]) {
+			t.Errorf("got result %x for %d bits, want prefix of %x", got, tt.bits, want)
+		}
+		if r := tt.bits % 8; r > 0 && got[len(got)-1]&(0xff>>r) != 0 {
+			t.Errorf("got result %x, low bits should be zero for %d bits", got, tt.bits)
+		}
+	}
+
+	for _, cfg := range []encrypt.BlindIndexConfig{{Key: testIndexKey[:16]}, {Key: testIndexKey, Bits: 257}, {Key: testIndexKey, Bits: -1}} {
+		if _, err := encrypt.NewBlindIndex(cfg); err == nil {
+			t.Errorf("config %+v should fail", cfg)
+		}
+	}
+	if _, err := encrypt.NewBlindIndex(encrypt.BlindIndexConfig{}); !errors.Is(err, encrypt.ErrBlindIndexKey) {
+		t.Errorf("got err %v, want %v", err, encrypt.ErrBlindIndexKey)
+	}
+}
+
+func TestNormalizers(t *testing.T) {
+	tests := []struct {
+		fn   func(string) string
+		in   string
+		want string
+	}{
+		{encrypt.NormalizeEmail, "  Bob@Example.COM\n", "bob@example.com"},
+		{encrypt.NormalizePhone, "138-0013 8000", "13800138000"},
+		{encrypt.NormalizePhone, " +86 (138) 0013.8000", "+8613800138000"},
+		{encrypt.NormalizePhone, "１３８", ""},
+		{encrypt.ChainNormalizers(encrypt.NormalizePhone, func(s string) string { return strings.TrimPrefix(s, "+86") }), "+86 138-0013-8000", "13800138000"},
+	}
+	for _, tt := range tests {
+		if got := tt.fn(tt.in); got != tt.want {
+			t.Errorf("got result %v, want %v", got, tt.want)
+		}
+	}
+}
@@ -6,15 +6,22 @@ import (
 	"github.com/supernarsi/gotool/encrypt/encrypt_date"
 )
 
+// DateEncrypt 将日期混淆为 11 位字符串
+//
+// Deprecated: 无密钥保护，任何人都可还原，新数据请使用 DateCipher
 func DateEncrypt(date time.Time) string {
 	dateString := date.Format("2006-01-02")
 	return DateStringEncrypt(dateString)
 }
 
+// DateStringEncrypt 混淆 YYYY-MM-DD 格式的日期，格式不符时返回空字符串
+//
+// Deprecated: 无密钥保护，任何人都可还原，新数据请使用 DateCipher
 func DateStringEncrypt(date string) string {
 	return encrypt_date.DateStringEncrypt(date)
 }
 
+// DateDecrypt 还原 DateEncrypt 生成的字符串，用于解析已存储的旧数据，失败时返回空字符串
 func DateDecrypt(encryptStr string) string {
 	return encrypt_date.DateDecrypt(encryptStr)
 }
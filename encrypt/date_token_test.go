@@ -0,0 +1,95 @@
+package encrypt_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/supernarsi/gotool/encrypt"
+)
+
+var testDateKey = bytes.Repeat([]byte{0x33}, 32)
+
+func TestDateCipher(t *testing.T) {
+	c, err := encrypt.NewDateCipher(testDateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shanghai := time.FixedZone("CST", 8*3600)
+	tests := []time.Time{
+		time.Date(2002, 2, 13, 0, 0, 0, 0, time.UTC),
+		time.Date(2000, 12, 31, 23, 59, 59, 999999999, shanghai),
+		time.Date(1969, 7, 20, 20, 17, 40, 0, time.FixedZone("", -5*3600-30*60)),
+		{},
+	}
+	urlSafe := regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	for _, tt := range tests {
+		t.Run(tt.String(), func(t *testing.T) {
+			token, err := c.Encrypt(tt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !urlSafe.MatchString(token) || len(token) > 48 {
+				t.Errorf("got token %v, want short url-safe token", token)
+			}
+			got, err := c.Decrypt(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt) || got.Format(time.RFC3339Nano) != tt.Format(time.RFC3339Nano) {
+				t.Errorf("got result %v, want %v", got, tt)
+			}
+		})
+	}
+}
+
+func TestDateCipherExpiry(t *testing.T) {
+	c, _ := encrypt.NewDateCipher(testDateKey)
+	date := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	token, err := c.EncryptWithExpiry(date, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.Decrypt(token); err != nil || !got.Equal(date) {
+		t.Errorf("got result %v %v, want %v", got, err, date)
+	}
+	if plain, _ := c.Encrypt(date); plain == token {
+		t.Error("token with expiry should differ from token without expiry")
+	}
+
+	token, _ = c.EncryptWithExpiry(date, time.Now().Add(-time.Second))
+	if _, err = c.Decrypt(token); !errors.Is(err, encrypt.ErrDateTokenExpired) {
+		t.Errorf("got err %v, want %v", err, encrypt.ErrDateTokenExpired)
+	}
+	if _, err = c.EncryptWithExpiry(date, time.Time{}); err == nil {
+		t.Error("zero expiry should fail")
+	}
+}
+
+func TestDateCipherInvalid(t *testing.T) {
+	c, _ := encrypt.NewDateCipher(testDateKey)
+	other, _ := encrypt.NewDateCipher(bytes.Repeat([]byte{0x44}, 32))
+	token, _ := c.Encrypt(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	raw, _ := base64.RawURLEncoding.DecodeString(token)
+	raw[len(raw)-1] ^= 1
+
+	for _, in := range []string{"", "1DBBD3BD3CE", "not base64!", base64.RawURLEncoding.EncodeToString(raw)} {
+		if _, err := c.Decrypt(in); !errors.Is(err, encrypt.ErrInvalidDateToken) {
+			t.Errorf("got err %v for %q, want %v", err, in, encrypt.ErrInvalidDateToken)
+		}
+	}
+	if _, err := other.Decrypt(token); !errors.Is(err, encrypt.ErrInvalidDateToken) {
+		t.Errorf("got err %v, want %v", err, encrypt.ErrInvalidDateToken)
+	}
+	if _, err := encrypt.NewDateCipher(testDateKey[:16]); !errors.Is(err, encrypt.ErrInvalidSIVKey) {
+		t.Errorf("got err %v, want %v", err, encrypt.ErrInvalidSIVKey)
+	}
+	// 旧令牌仍由 DateDecrypt 解析
+	if got := encrypt.DateDecrypt("1DBBD3BD3CE"); got != "2002-02-13" {
+		t.Errorf("got result %v, want %v", got, "2002-02-13")
+	}
+}
@@ -1,6 +1,8 @@
 package encrypt
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
 	"encoding/binary"
 	"encoding/hex"
@@ -11,11 +13,22 @@ import (
 const (
 	ipv4len         = net.IPv4len
 	ipEncryptKeyLen = 16
+	ipFeistelRounds = 10
+)
+
+// IPMode IP 加密方式
+type IPMode int
+
+const (
+	IPModeLegacy IPMode = iota // 旧的加法方案，仅支持 IPv4，密文为 16 位 hex，仅用于解密已存储的数据
+	IPModeFPE                  // 基于 AES 的 Feistel 置换，密文为同一地址族的合法 IP
 )
 
 var (
 	ErrIPBeyondIPv4 = errors.New("IP address beyond the scope of IPv4")
 	ErrInvalidIPHex = errors.New("invalid IP address hex")
+	ErrInvalidIP    = errors.New("invalid IP address")
+	ErrInvalidIPKey = errors.New("invalid encryption key, must be 16, 24 or 32 bytes hex")
 	defaultIPKey    = []byte{0x72, 0x4E, 0x64, 0x33, 0x54, 0x51, 0x71, 0x39, 0x42, 0x64, 0x54, 0x41, 0x4B, 0x46, 0x34, 0x32} // "rNd3TQq9BdTAKF42" 的 hex 表示
 )
 
@@ -29,11 +42,30 @@ func newIPKey() ([]byte, error) {
 	return key, nil
 }
 
+// IPEncryptor IP 地址加密，加密方式由构造函数决定
 type IPEncryptor struct {
-	key uint64
+	mode  IPMode
+	key   uint64       // IPModeLegacy
+	block cipher.Block // IPModeFPE
+}
+
+// NewIPEncryptorFPE 使用 AES 密钥创建保留格式的加密实例，支持 IPv4 与 IPv6
+// 密文仍是同一地址族的合法 IP，相同密钥下同一地址的密文固定
+func NewIPEncryptorFPE(hexKey string) (*IPEncryptor, error) {
+	keyBytes, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, ErrInvalidIPKey
+	}
+	block, err := aes.NewCipher(keyBytes)
+	if err != nil {
+		return nil, ErrInvalidIPKey
+	}
+	return &IPEncryptor{mode: IPModeFPE, block: block}, nil
 }
 
-// NewIPEncryptorWithKey 允许传入密钥，未提供则使用默认密钥
+// NewIPEncryptorWithKey 允许传入密钥，未提供则使用默认密钥，使用 IPModeLegacy 方式
+//
+// Deprecated: 加法方案可由两组明密文推出密钥，仅用于解密已存储的数据，新数据请使用 NewIPEncryptorFPE
 func NewIPEncryptorWithKey(hexKey string) (*IPEncryptor, error) {
 	var keyBytes []byte
 	var err error
@@ -47,16 +79,28 @@ func NewIPEncryptorWithKey(hexKey string) (*IPEncryptor, error) {
 		}
 	}
 
-	return &IPEncryptor{key: binary.BigEndian.Uint64(keyBytes)}, nil
+	return &IPEncryptor{mode: IPModeLegacy, key: binary.BigEndian.Uint64(keyBytes)}, nil
 }
 
-// NewIPEncryptor 使用默认密钥
+// NewIPEncryptor 使用默认密钥，使用 IPModeLegacy 方式
+//
+// Deprecated: 默认密钥公开且加法方案可被轻易还原，新数据请使用 NewIPEncryptorFPE
 func NewIPEncryptor() *IPEncryptor {
-	return &IPEncryptor{key: binary.BigEndian.Uint64(defaultIPKey)}
+	return &IPEncryptor{mode: IPModeLegacy, key: binary.BigEndian.Uint64(defaultIPKey)}
+}
+
+// Mode 加密方式
+func (enc *IPEncryptor) Mode() IPMode {
+	return enc.mode
 }
 
 // Encrypt 加密 IP 地址
+// IPModeFPE 返回同一地址族的 IP，IPv4 映射的 IPv6 地址按 IPv4 处理；IPModeLegacy 返回 hex 字符串
 func (enc *IPEncryptor) Encrypt(ip string) (string, error) {
+	if enc.mode == IPModeFPE {
+		return enc.permute(ip, false)
+	}
+
 	ipBytes := net.ParseIP(ip).To4()
 	if ipBytes == nil {
 		return "", ErrIPBeyondIPv4
@@ -71,8 +115,12 @@ func (enc *IPEncryptor) Encrypt(ip string) (string, error) {
 	return hex.EncodeToString(buf[:]), nil
 }
 
-// Decrypt 解密 IP 地址
+// Decrypt 解密 IP 地址，输入格式与 Encrypt 的返回值一致
 func (enc *IPEncryptor) Decrypt(ipHex string) (string, error) {
+	if enc.mode == IPModeFPE {
+		return enc.permute(ipHex, true)
+	}
+
 	if ipHex == "" {
 		return "", errors.New("empty encrypted IP string")
 	}
@@ -93,3 +141,64 @@ func (enc *IPEncryptor) Decrypt(ipHex string) (string, error) {
 	ip := net.IPv4(byte(ipUint32>>24), byte(ipUint32>>16), byte(ipUint32>>8), byte(ipUint32))
 	return ip.String(), nil
 }
+
+// permute 解析地址后执行 Feistel 置换
+func (enc *IPEncryptor) permute(ip string, decrypt bool) (string, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", ErrInvalidIP
+	}
+	b := parsed.To4()
+	if b == nil {
+		b = append(net.IP(nil), parsed...)
+	}
+	// IPv6 的结果落在 IPv4 映射段（::ffff:0:0/96）时会被当作 IPv4，继续置换直到离开该段（cycle walking）
+	for {
+		enc.feistel(b, decrypt)
+		if len(b) == ipv4len || b.To4() == nil {
+			return b.String(), nil
+		}
+	}
+}
+
+// feistel 原地执行平衡 Feistel 网络，b 为 4 或 16 字节，左右两半各 16 或 64 位
+// 轮函数为 AES(地址长度 || 轮数 || 右半部分) 截断到半块长度
+func (enc *IPEncryptor) feistel(b []byte, decrypt bool) {
+	n := len(b) / 2
+	mask := uint64(1)<<(8*n) - 1
+	l, r := beUint(b[:n]), beUint(b[n:])
+	var in, out [aes.BlockSize]byte
+	in[0] = byte(len(b))
+	round := func(i int, x uint64) uint64 {
+		in[1] = byte(i)
+		binary.BigEndian.PutUint64(in[8:], x)
+		enc.block.Encrypt(out[:], in[:])
+		return binary.BigEndian.Uint64(out[:8]) & mask
+	}
+	if decrypt {
+		for i := ipFeistelRounds - 1; i >= 0; i-- {
+			l, r = r^round(i, l), l
+		}
+	} else {
+		for i := 0; i < ipFeistelRounds; i++ {
+			l, r = r, l^round(i, r)
+		}
+	}
+	putBeUint(b[:n], l)
+	putBeUint(b[n:], r)
+}
+
+func beUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func putBeUint(b []byte, v uint64) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+}
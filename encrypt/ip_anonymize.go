@@ -0,0 +1,157 @@
+package encrypt
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+)
+
+const ipAnonymizerKeyLen = 32
+
+var ErrInvalidAnonymizerKey = errors.New("invalid anonymization key, must be 32 bytes hex")
+
+// 文本中的 IPv6（含内嵌 IPv4）与 IPv4 候选，是否为合法地址由 net.ParseIP 判断
+var ipCandidate = regexp.MustCompile(`(?:[0-9A-Fa-f]{0,4}:){2,7}(?:\d{1,3}(?:\.\d{1,3}){3}|[0-9A-Fa-f]{1,4})?|\d{1,3}(?:\.\d{1,3}){3}`)
+
+// IPAnonymizer 前缀保留的 IP 匿名化（Crypto-PAn）
+// 两个地址的公共前缀有多长，匿名化后的公共前缀就有多长，同一 /24 内的地址匿名化后仍在同一 /24 内
+type IPAnonymizer struct {
+	block cipher.Block
+	pad   [aes.BlockSize]byte
+}
+
+// NewIPAnonymizer 使用 32 字节密钥创建匿名化实例，前 16 字节为 AES 密钥，后 16 字节用于生成填充
+func NewIPAnonymizer(hexKey string) (*IPAnonymizer, error) {
+	keyBytes, err := hex.DecodeString(hexKey)
+	if err != nil || len(keyBytes) != ipAnonymizerKeyLen {
+		return nil, ErrInvalidAnonymizerKey
+	}
+	a := &IPAnonymizer{}
+	if a.block, err = aes.NewCipher(keyBytes[:aes.BlockSize]); err != nil {
+		return nil, err
+	}
+	a.block.Encrypt(a.pad[:], keyBytes[aes.BlockSize:])
+	return a, nil
+}
+
+// Anonymize 匿名化 IP 地址，返回同一地址族的 IP，IPv4 映射的 IPv6 地址按 IPv4 处理
+func (a *IPAnonymizer) Anonymize(ip string) (string, error) {
+	parsed := parseIP(ip)
+	if parsed == nil {
+		return "", ErrInvalidIP
+	}
+	return a.anonymize(parsed, false).String(), nil
+}
+
+// Deanonymize 还原 Anonymize 的结果，需持有相同密钥
+func (a *IPAnonymizer) Deanonymize(ip string) (string, error) {
+	parsed := parseIP(ip)
+	if parsed == nil {
+		return "", ErrInvalidIP
+	}
+	return a.anonymize(parsed, true).String(), nil
+}
+
+// AnonymizeText 将文本中的 IPv4 与 IPv6 地址替换为匿名化结果，其余内容保持不变
+// 地址与前后的字母、数字、点或冒号相连时不替换，IPv4 后的冒号视为端口分隔符
+func (a *IPAnonymizer) AnonymizeText(text string) string {
+	matches := ipCandidate.FindAllStringIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+	var b strings.Builder
+	b.Grow(len(text))
+	last := 0
+	for _, m := range matches {
+		s := text[m[0]:m[1]]
+		ip := parseIP(s)
+		if ip == nil || !ipBoundary(text, m[0], m[1], strings.Contains(s, ":")) {
+			continue
+		}
+		b.WriteString(text[last:m[0]])
+		b.WriteString(a.anonymize(ip, false).String())
+		last = m[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// AnonymizeStream 逐行读取 r，替换其中的 IP 地址后写入 w，适用于日志文件
+func (a *IPAnonymizer) AnonymizeStream(w io.Writer, r io.Reader) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			if _, werr := bw.WriteString(a.AnonymizeText(line)); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return bw.Flush()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// anonymize 逐位计算：第 i 位与 AES(原地址前 i 位 || 填充的其余位) 的最高位异或
+// 还原时原地址的前 i 位已在前面的步骤中得到，因此可以逐位反推
+func (a *IPAnonymizer) anonymize(ip net.IP, reverse bool) net.IP {
+	out := make(net.IP, len(ip))
+	orig := ip
+	if reverse {
+		orig = out
+	}
+	var in, res [aes.BlockSize]byte
+	for pos := 0; pos < len(ip)*8; pos++ {
+		n, r := pos/8, pos%8
+		in = a.pad
+		copy(in[:n], orig[:n])
+		if r > 0 {
+			mask := byte(0xff) << (8 - r)
+			in[n] = orig[n]&mask | a.pad[n]&^mask
+		}
+		a.block.Encrypt(res[:], in[:])
+		bit := byte(0x80) >> r
+		out[n] |= ip[n]&bit ^ res[0]>>r&bit
+	}
+	return out
+}
+
+// parseIP 解析地址，IPv4 返回 4 字节形式
+func parseIP(s string) net.IP {
+	ip := net.ParseIP(s)
+	if v4 := ip.To4(); v4 != nil {
+		return v4
+	}
+	return ip
+}
+
+// ipBoundary 匹配到的地址是否是独立的词，地址后作为句号的点不影响判断
+func ipBoundary(text string, start, end int, v6 bool) bool {
+	isWord := func(c byte) bool {
+		return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+	}
+	if start > 0 {
+		if c := text[start-1]; isWord(c) || c == '.' || c == ':' {
+			return false
+		}
+	}
+	if end < len(text) {
+		if c := text[end]; isWord(c) || v6 && c == ':' {
+			return false
+		}
+		if text[end] == '.' && end+1 < len(text) && isWord(text[end+1]) {
+			return false
+		}
+	}
+	return true
+}
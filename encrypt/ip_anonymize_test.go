@@ -0,0 +1,134 @@
+package encrypt_test
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/supernarsi/gotool/encrypt"
+)
+
+// Crypto-PAn 参考实现的测试密钥
+const testAnonymizerKey = "1522178d33a4cf80130a5b1649907d10d8988f837979652762574c2d2a842202"
+
+func TestIPAnonymize(t *testing.T) {
+	a, err := encrypt.NewIPAnonymizer(testAnonymizerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in   string
+		want string
+		err  error
+	}{
+		{"128.11.68.132", "135.242.180.132", nil},
+		{"129.118.74.4", "134.136.186.123", nil},
+		{"130.132.252.244", "133.68.164.234", nil},
+		{"141.223.7.43", "141.167.8.160", nil},
+		{"192.102.249.13", "252.138.62.131", nil},
+		{"::ffff:128.11.68.132", "135.242.180.132", nil},
+		{"::1", "78ff:f001:9fc0:20df:8380:b1f1:704:ed", nil},
+		{"::2", "78ff:f001:9fc0:20df:8380:b1f1:704:ef", nil},
+		{"2001:db8::1", "4401:2bc:603f:d91d:27f:ff8e:e6f1:dc1e", nil},
+		{"", "", encrypt.ErrInvalidIP},
+		{"1.2.3", "", encrypt.ErrInvalidIP},
+	}
+	for _, tt := range tests {
+		t.Run("test for "+tt.in, func(t *testing.T) {
+			got, err := a.Anonymize(tt.in)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got err %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("got result %v, want %v", got, tt.want)
+			}
+			if tt.err != nil {
+				return
+			}
+			if plain, err := a.Deanonymize(got); err != nil || plain != net.ParseIP(tt.in).String() {
+				t.Errorf("got result %v %v, want %v", plain, err, tt.in)
+			}
+		})
+	}
+}
+
+func TestIPAnonymizePrefix(t *testing.T) {
+	a, _ := encrypt.NewIPAnonymizer(testAnonymizerKey)
+	tests := []struct {
+		x, y   string
+		prefix int
+	}{
+		{"10.1.2.3", "10.1.2.200", 24},
+		{"10.1.2.3", "10.1.3.3", 23},
+		{"10.1.2.3", "138.1.2.3", 0},
+		{"2001:db8:1::1", "2001:db8:1::ffff", 112},
+		{"2001:db8:1::1", "2001:db8:2::1", 46},
+	}
+	for _, tt := range tests {
+		x, _ := a.Anonymize(tt.x)
+		y, _ := a.Anonymize(tt.y)
+		if got := commonPrefix(net.ParseIP(x), net.ParseIP(y)); got != tt.prefix {
+			t.Errorf("got prefix %v for %v %v, want %v", got, x, y, tt.prefix)
+		}
+	}
+}
+
+func commonPrefix(x, y net.IP) int {
+	if v4 := x.To4(); v4 != nil {
+		x, y = v4, y.To4()
+	}
+	for i := range x {
+		if d := x[i] ^ y[i]; d != 0 {
+			n := i * 8
+			for ; d&0x80 == 0; d <<= 1 {
+				n++
+			}
+			return n
+		}
+	}
+	return len(x) * 8
+}
+
+func TestIPAnonymizeText(t *testing.T) {
+	a, _ := encrypt.NewIPAnonymizer(testAnonymizerKey)
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"GET / from 128.11.68.132", "GET / from 135.242.180.132"},
+		{"128.11.68.132:8080 -> [::1]:443", "135.242.180.132:8080 -> [78ff:f001:9fc0:20df:8380:b1f1:704:ed]:443"},
+		{"client=129.118.74.4, user=u1", "client=134.136.186.123, user=u1"},
+		{"last seen 141.223.7.43.", "last seen 141.167.8.160."},
+		{"version 1.2.3.4.5 at 12:30:45 mac aa:bb:cc:dd:ee:ff", "version 1.2.3.4.5 at 12:30:45 mac aa:bb:cc:dd:ee:ff"},
+		{"v1.2.3.4 999.1.1.1", "v1.2.3.4 999.1.1.1"},
+	}
+	for _, tt := range tests {
+		if got := a.AnonymizeText(tt.in); got != tt.want {
+			t.Errorf("got result %v, want %v", got, tt.want)
+		}
+	}
+	// 保留 IPv6 的接口名
+	ll, _ := a.Anonymize("fe80::1")
+	if got, want := a.AnonymizeText("via fe80::1%eth0"), "via "+ll+"%eth0"; got != want {
+		t.Errorf("got result %v, want %v", got, want)
+	}
+
+	var out bytes.Buffer
+	in := "a 128.11.68.132\nb ::2\n\nc"
+	if err := a.AnonymizeStream(&out, strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a 135.242.180.132\nb 78ff:f001:9fc0:20df:8380:b1f1:704:ef\n\nc"; out.String() != want {
+		t.Errorf("got result %q, want %q", out.String(), want)
+	}
+}
+
+func TestNewIPAnonymizer(t *testing.T) {
+	for _, key := range []string{"", "zz", testIPKey} {
+		if _, err := encrypt.NewIPAnonymizer(key); !errors.Is(err, encrypt.ErrInvalidAnonymizerKey) {
+			t.Errorf("got err %v for key %q, want %v", err, key, encrypt.ErrInvalidAnonymizerKey)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package encrypt_test
 
 import (
 	"errors"
+	"fmt"
+	"net"
 	"testing"
 
 	"github.com/supernarsi/gotool/encrypt"
@@ -90,3 +92,89 @@ func TestIpDecrypt2(t *testing.T) {
 		})
 	}
 }
+
+const testIPKey = "000102030405060708090a0b0c0d0e0f"
+
+func TestIpFPE(t *testing.T) {
+	enc, err := encrypt.NewIPEncryptorFPE(testIPKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc.Mode() != encrypt.IPModeFPE {
+		t.Errorf("got mode %v, want %v", enc.Mode(), encrypt.IPModeFPE)
+	}
+	tests := []struct {
+		in   string
+		want string
+		err  error
+	}{
+		// 固定向量，算法变化会导致已存储的密文无法解密
+		{"127.0.0.1", "182.152.218.203", nil},
+		{"0.0.0.0", "228.5.58.71", nil},
+		{"::ffff:127.0.0.1", "182.152.218.203", nil},
+		{"2001:db8::1", "79c9:7f56:9508:b8e6:4d8c:1d77:57e:f3f0", nil},
+		{"::", "93c2:f2f3:445:ce8e:cd54:dd46:ebc6:6a96", nil},
+		{"", "", encrypt.ErrInvalidIP},
+		{"255.255.255.999", "", encrypt.ErrInvalidIP},
+		{"fe80::1%eth0", "", encrypt.ErrInvalidIP},
+	}
+	for _, tt := range tests {
+		t.Run("test for "+tt.in, func(t *testing.T) {
+			got, err := enc.Encrypt(tt.in)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got err %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("got result %v, want %v", got, tt.want)
+			}
+			if tt.err != nil {
+				return
+			}
+			plain, err := enc.Decrypt(got)
+			if want := net.ParseIP(tt.in).String(); err != nil || plain != want {
+				t.Errorf("got result %v %v, want %v", plain, err, want)
+			}
+		})
+	}
+}
+
+func TestIpFPERoundTrip(t *testing.T) {
+	enc, _ := encrypt.NewIPEncryptorFPE(testIPKey + testIPKey)
+	other, _ := encrypt.NewIPEncryptorFPE("ffeeddccbbaa99887766554433221100")
+	seen := make(map[string]bool)
+	for i := 0; i < 2000; i++ {
+		ip := net.IPv4(10, 0, byte(i>>8), byte(i)).String()
+		if i%2 == 1 {
+			ip = fmt.Sprintf("2001:db8::%x", i)
+		}
+		got, err := enc.Encrypt(ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := net.ParseIP(got)
+		if c == nil || (c.To4() != nil) != (i%2 == 0) {
+			t.Fatalf("got %v for %v, want same address family", got, ip)
+		}
+		if seen[got] {
+			t.Fatalf("duplicate ciphertext %v", got)
+		}
+		seen[got] = true
+		if plain, err := enc.Decrypt(got); err != nil || plain != ip {
+			t.Fatalf("got result %v %v, want %v", plain, err, ip)
+		}
+		if o, _ := other.Encrypt(ip); o == got {
+			t.Errorf("different keys give same ciphertext %v for %v", got, ip)
+		}
+	}
+}
+
+func TestNewIPEncryptorFPE(t *testing.T) {
+	for _, key := range []string{"", "zz", "0001020304050607", testIPKey + "00"} {
+		if _, err := encrypt.NewIPEncryptorFPE(key); !errors.Is(err, encrypt.ErrInvalidIPKey) {
+			t.Errorf("got err %v for key %q, want %v", err, key, encrypt.ErrInvalidIPKey)
+		}
+	}
+	if got := encrypt.NewIPEncryptor().Mode(); got != encrypt.IPModeLegacy {
+		t.Errorf("got mode %v, want %v", got, encrypt.IPModeLegacy)
+	}
+}
@@ -0,0 +1,185 @@
+package encrypt
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	envelopeVersion  = 1
+	envelopeHeadLen  = 3 // 版本、算法、密钥 ID 长度
+	maxKeyringIDSize = 255
+)
+
+var (
+	ErrNoActiveKey  = errors.New("keyring has no active key")
+	ErrUnknownKeyID = errors.New("unknown key id")
+	ErrInvalidKeyID = errors.New("invalid key id, must be 1 to 255 bytes")
+)
+
+// Keyring 持有多个密钥，使用当前密钥加密，按信封中的密钥 ID 选择密钥解密，可并发使用
+// 信封格式：版本(1) || 算法(1) || 密钥 ID 长度(1) || 密钥 ID || nonce || 密文 || tag，头部同时参与认证
+type Keyring struct {
+	mu     sync.RWMutex
+	keys   map[string]keyringEntry
+	active string
+}
+
+type keyringEntry struct {
+	alg  Algorithm
+	aead cipher.AEAD
+}
+
+// NewKeyring 创建空的密钥环
+func NewKeyring() *Keyring {
+	return &Keyring{keys: make(map[string]keyringEntry)}
+}
+
+// Add 添加 32 字节密钥，第一个添加的密钥成为当前密钥，ID 已存在时返回错误
+func (k *Keyring) Add(id string, alg Algorithm, key []byte) error {
+	if len(id) == 0 || len(id) > maxKeyringIDSize {
+		return ErrInvalidKeyID
+	}
+	aead, err := newAEAD(alg, key)
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[id]; ok {
+		return fmt.Errorf("duplicate key id %q", id)
+	}
+	k.keys[id] = keyringEntry{alg: alg, aead: aead}
+	if k.active == "" {
+		k.active = id
+	}
+	return nil
+}
+
+// SetActive 设置用于加密的密钥
+func (k *Keyring) SetActive(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[id]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKeyID, id)
+	}
+	k.active = id
+	return nil
+}
+
+// Active 当前密钥的 ID
+func (k *Keyring) Active() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active
+}
+
+// Remove 移除不再使用的密钥，当前密钥不能移除
+func (k *Keyring) Remove(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if id == k.active {
+		return fmt.Errorf("cannot remove active key %q", id)
+	}
+	if _, ok := k.keys[id]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKeyID, id)
+	}
+	delete(k.keys, id)
+	return nil
+}
+
+// Seal 使用当前密钥加密，返回带密钥 ID 的信封
+func (k *Keyring) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	k.mu.RLock()
+	id, entry := k.active, k.keys[k.active]
+	k.mu.RUnlock()
+	if id == "" {
+		return nil, ErrNoActiveKey
+	}
+
+	head := make([]byte, 0, envelopeHeadLen+len(id)+entry.aead.NonceSize()+len(plaintext)+entry.aead.Overhead())
+	head = append(head, envelopeVersion, byte(entry.alg), byte(len(id)))
+	head = append(head, id...)
+	return seal(entry.aead, head, plaintext, envelopeAD(head, additionalData))
+}
+
+// Open 按信封中的密钥 ID 解密，additionalData 需与加密时一致
+func (k *Keyring) Open(envelope, additionalData []byte) ([]byte, error) {
+	id, n, err := parseEnvelope(envelope)
+	if err != nil {
+		return nil, err
+	}
+	k.mu.RLock()
+	entry, ok := k.keys[id]
+	k.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKeyID, id)
+	}
+	if Algorithm(envelope[1]) != entry.alg {
+		return nil, ErrDecrypt
+	}
+	return open(entry.aead, envelope[n:], envelopeAD(envelope[:n], additionalData))
+}
+
+// SealString 加密字符串，返回 URL 安全的 base64（无填充），便于存入文本字段
+func (k *Keyring) SealString(plaintext string, additionalData []byte) (string, error) {
+	envelope, err := k.Seal([]byte(plaintext), additionalData)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(envelope), nil
+}
+
+// OpenString 解密 SealString 的结果
+func (k *Keyring) OpenString(s string, additionalData []byte) (string, error) {
+	envelope, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	plaintext, err := k.Open(envelope, additionalData)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Reencrypt 解密后使用当前密钥重新加密，用于密钥轮换
+// 信封已由当前密钥加密时验证后原样返回，调用方可据此跳过写回
+func (k *Keyring) Reencrypt(envelope, additionalData []byte) ([]byte, error) {
+	plaintext, err := k.Open(envelope, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	if id, _ := EnvelopeKeyID(envelope); id == k.Active() {
+		return envelope, nil
+	}
+	return k.Seal(plaintext, additionalData)
+}
+
+// EnvelopeKeyID 返回信封使用的密钥 ID，不解密内容
+func EnvelopeKeyID(envelope []byte) (string, error) {
+	id, _, err := parseEnvelope(envelope)
+	return id, err
+}
+
+// parseEnvelope 校验信封头部，返回密钥 ID 与头部长度
+func parseEnvelope(envelope []byte) (string, int, error) {
+	if len(envelope) < envelopeHeadLen || envelope[0] != envelopeVersion {
+		return "", 0, ErrInvalidCiphertext
+	}
+	n := envelopeHeadLen + int(envelope[2])
+	if envelope[2] == 0 || len(envelope) < n {
+		return "", 0, ErrInvalidCiphertext
+	}
+	return string(envelope[envelopeHeadLen:n]), n, nil
+}
+
+// envelopeAD 头部 || 调用方的附加数据
+func envelopeAD(head, additionalData []byte) []byte {
+	ad := make([]byte, 0, len(head)+len(additionalData))
+	ad = append(ad, head...)
+	return append(ad, additionalData...)
+}
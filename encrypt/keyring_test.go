@@ -0,0 +1,111 @@
+package encrypt_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/supernarsi/gotool/encrypt"
+)
+
+func TestKeyring(t *testing.T) {
+	ring := encrypt.NewKeyring()
+	if _, err := ring.Seal([]byte("x"), nil); !errors.Is(err, encrypt.ErrNoActiveKey) {
+		t.Errorf("got err %v, want %v", err, encrypt.ErrNoActiveKey)
+	}
+	if err := ring.Add("2024-01", encrypt.AES256GCM, testAEADKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.Add("2024-01", encrypt.AES256GCM, testAEADKey); err == nil {
+		t.Error("duplicate key id should fail")
+	}
+	if err := ring.Add("", encrypt.AES256GCM, testAEADKey); !errors.Is(err, encrypt.ErrInvalidKeyID) {
+		t.Errorf("got err %v, want %v", err, encrypt.ErrInvalidKeyID)
+	}
+
+	ad := []byte("users.phone:1")
+	old, err := ring.SealString("13800138000", ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 轮换：新密钥加密，旧密钥仍可解密
+	if err = ring.Add("2024-06", encrypt.XChaCha20Poly1305, testAEADKey2); err != nil {
+		t.Fatal(err)
+	}
+	if got := ring.Active(); got != "2024-01" {
+		t.Errorf("got active %v, want %v", got, "2024-01")
+	}
+	if err = ring.SetActive("2024-06"); err != nil {
+		t.Fatal(err)
+	}
+	cur, _ := ring.SealString("13800138000", ad)
+	for _, s := range []string{old, cur} {
+		if got, err := ring.OpenString(s, ad); err != nil || got != "13800138000" {
+			t.Errorf("got result %v %v, want %v", got, err, "13800138000")
+		}
+	}
+	if _, err = ring.OpenString(cur, []byte("users.phone:2")); !errors.Is(err, encrypt.ErrDecrypt) {
+		t.Errorf("got err %v, want %v", err, encrypt.ErrDecrypt)
+	}
+	if _, err = ring.OpenString("not base64!", ad); !errors.Is(err, encrypt.ErrInvalidCiphertext) {
+		t.Errorf("got err %v, want %v", err, encrypt.ErrInvalidCiphertext)
+	}
+
+	envelope, _ := ring.Seal([]byte("a"), nil)
+	ring.SetActive("2024-01")
+	envelope2, _ := ring.Seal([]byte("a"), nil)
+	ring.SetActive("2024-06")
+	rotated, err := ring.Reencrypt(envelope2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := encrypt.EnvelopeKeyID(rotated); id != "2024-06" {
+		t.Errorf("got key id %v, want %v", id, "2024-06")
+	}
+	if same, _ := ring.Reencrypt(envelope, nil); !bytes.Equal(same, envelope) {
+		t.Error("envelope under active key should be returned unchanged")
+	}
+
+	if err = ring.Remove("2024-06"); err == nil {
+		t.Error("removing active key should fail")
+	}
+	if err = ring.Remove("2024-01"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ring.Open(envelope2, nil); !errors.Is(err, encrypt.ErrUnknownKeyID) {
+		t.Errorf("got err %v, want %v", err, encrypt.ErrUnknownKeyID)
+	}
+}
+
+func TestKeyringEnvelope(t *testing.T) {
+	ring := encrypt.NewKeyring()
+	_ = ring.Add("k1", encrypt.AES256GCM, testAEADKey)
+	envelope, _ := ring.Seal([]byte("secret"), nil)
+
+	// 修改头部中的算法或密钥 ID 都会导致认证失败
+	other := encrypt.NewKeyring()
+	_ = other.Add("k1", encrypt.XChaCha20Poly1305, testAEADKey)
+	_ = other.Add("k2", encrypt.AES256GCM, testAEADKey)
+	renamed := append([]byte(nil), envelope...)
+	renamed[4] = '2'
+
+	tests := []struct {
+		name string
+		ring *encrypt.Keyring
+		in   []byte
+		err  error
+	}{
+		{"empty", ring, nil, encrypt.ErrInvalidCiphertext},
+		{"bad version", ring, append([]byte{9}, envelope[1:]...), encrypt.ErrInvalidCiphertext},
+		{"truncated id", ring, envelope[:4], encrypt.ErrInvalidCiphertext},
+		{"truncated body", ring, envelope[:10], encrypt.ErrInvalidCiphertext},
+		{"algorithm mismatch", other, envelope, encrypt.ErrDecrypt},
+		{"renamed key id", other, renamed, encrypt.ErrDecrypt},
+	}
+	for _, tt := range tests {
+		if _, err := tt.ring.Open(tt.in, nil); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got err %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
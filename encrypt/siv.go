@@ -0,0 +1,137 @@
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+)
+
+var ErrInvalidSIVKey = errors.New("invalid SIV key, must be 32, 48 or 64 bytes")
+
+// SIV AES-SIV 确定性认证加密（RFC 5297），相同密钥、明文与附加数据得到相同密文，可用于等值查询
+// 密文会暴露明文是否相同，只应用于需要等值查询的字段，其余字段使用 Keyring
+type SIV struct {
+	mac cipher.Block // K1，用于 S2V
+	ctr cipher.Block // K2，用于 CTR 加密
+}
+
+// NewSIV 密钥为 32、48 或 64 字节，分别对应 AES-128、AES-192、AES-256，前一半用于认证，后一半用于加密
+func NewSIV(key []byte) (*SIV, error) {
+	if n := len(key); n != 32 && n != 48 && n != 64 {
+		return nil, ErrInvalidSIVKey
+	}
+	half := len(key) / 2
+	mac, err := aes.NewCipher(key[:half])
+	if err != nil {
+		return nil, err
+	}
+	ctr, err := aes.NewCipher(key[half:])
+	if err != nil {
+		return nil, err
+	}
+	return &SIV{mac: mac, ctr: ctr}, nil
+}
+
+// Seal 加密明文，返回 16 字节合成 IV || 密文，附加数据按顺序参与认证
+func (s *SIV) Seal(plaintext []byte, additionalData ...[]byte) []byte {
+	v := s.s2v(plaintext, additionalData)
+	out := make([]byte, aes.BlockSize+len(plaintext))
+	copy(out, v[:])
+	s.xorKeyStream(out[aes.BlockSize:], plaintext, v)
+	return out
+}
+
+// Open 解密 Seal 的结果，附加数据需与加密时一致
+func (s *SIV) Open(ciphertext []byte, additionalData ...[]byte) ([]byte, error) {
+	if len(ciphertext) < aes.BlockSize {
+		return nil, ErrInvalidCiphertext
+	}
+	var v [aes.BlockSize]byte
+	copy(v[:], ciphertext)
+	plaintext := make([]byte, len(ciphertext)-aes.BlockSize)
+	s.xorKeyStream(plaintext, ciphertext[aes.BlockSize:], v)
+	t := s.s2v(plaintext, additionalData)
+	if subtle.ConstantTimeCompare(t[:], v[:]) != 1 {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// xorKeyStream 以清除第 31 与 63 位后的 IV 作为 CTR 初始计数器
+func (s *SIV) xorKeyStream(dst, src []byte, v [aes.BlockSize]byte) {
+	v[8] &= 0x7f
+	v[12] &= 0x7f
+	cipher.NewCTR(s.ctr, v[:]).XORKeyStream(dst, src)
+}
+
+// s2v RFC 5297 2.4，输入为各附加数据与明文
+func (s *SIV) s2v(plaintext []byte, additionalData [][]byte) [aes.BlockSize]byte {
+	var zero [aes.BlockSize]byte
+	d := cmac(s.mac, zero[:])
+	for _, ad := range additionalData {
+		d = dbl(d)
+		xorBlock(&d, cmac(s.mac, ad))
+	}
+	if len(plaintext) >= aes.BlockSize {
+		t := append([]byte(nil), plaintext...)
+		for i := range d {
+			t[len(t)-aes.BlockSize+i] ^= d[i]
+		}
+		return cmac(s.mac, t)
+	}
+	var t [aes.BlockSize]byte
+	copy(t[:], plaintext)
+	t[len(plaintext)] = 0x80
+	d = dbl(d)
+	xorBlock(&d, t)
+	return cmac(s.mac, d[:])
+}
+
+// cmac AES-CMAC（RFC 4493）
+func cmac(b cipher.Block, msg []byte) [aes.BlockSize]byte {
+	var k1, mac [aes.BlockSize]byte
+	b.Encrypt(k1[:], k1[:])
+	k1 = dbl(k1)
+
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	if n == 0 {
+		n = 1
+	}
+	for i := 0; i < n-1; i++ {
+		for j := 0; j < aes.BlockSize; j++ {
+			mac[j] ^= msg[i*aes.BlockSize+j]
+		}
+		b.Encrypt(mac[:], mac[:])
+	}
+
+	var last [aes.BlockSize]byte
+	rest := msg[(n-1)*aes.BlockSize:]
+	copy(last[:], rest)
+	if len(rest) == aes.BlockSize {
+		xorBlock(&last, k1)
+	} else {
+		last[len(rest)] = 0x80
+		xorBlock(&last, dbl(k1))
+	}
+	xorBlock(&mac, last)
+	b.Encrypt(mac[:], mac[:])
+	return mac
+}
+
+// dbl GF(2^128) 上乘以 x
+func dbl(b [aes.BlockSize]byte) [aes.BlockSize]byte {
+	var out [aes.BlockSize]byte
+	carry := b[0] >> 7
+	for i := 0; i < aes.BlockSize-1; i++ {
+		out[i] = b[i]<<1 | b[i+1]>>7
+	}
+	out[aes.BlockSize-1] = b[aes.BlockSize-1]<<1 ^ 0x87*carry
+	return out
+}
+
+func xorBlock(dst *[aes.BlockSize]byte, src [aes.BlockSize]byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
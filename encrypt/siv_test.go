@@ -0,0 +1,77 @@
+package encrypt_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/supernarsi/gotool/encrypt"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSIV(t *testing.T) {
+	// RFC 5297 附录 A.1
+	key := mustHex(t, "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff")
+	ad := mustHex(t, "101112131415161718191a1b1c1d1e1f2021222324252627")
+	plaintext := mustHex(t, "112233445566778899aabbccddee")
+	want := mustHex(t, "85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c")
+
+	s, err := encrypt.NewSIV(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s.Seal(plaintext, ad)
+	if !bytes.Equal(got, want) {
+		t.Errorf("got result %x, want %x", got, want)
+	}
+	if p, err := s.Open(got, ad); err != nil || !bytes.Equal(p, plaintext) {
+		t.Errorf("got result %x %v, want %x", p, err, plaintext)
+	}
+
+	tampered := append([]byte(nil), got...)
+	tampered[len(tampered)-1] ^= 1
+	tests := []struct {
+		name string
+		in   []byte
+		ad   [][]byte
+		err  error
+	}{
+		{"tampered", tampered, [][]byte{ad}, encrypt.ErrDecrypt},
+		{"missing ad", got, nil, encrypt.ErrDecrypt},
+		{"extra ad", got, [][]byte{ad, nil}, encrypt.ErrDecrypt},
+		{"short", got[:15], [][]byte{ad}, encrypt.ErrInvalidCiphertext},
+	}
+	for _, tt := range tests {
+		if _, err := s.Open(tt.in, tt.ad...); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got err %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestSIVDeterministic(t *testing.T) {
+	s, _ := encrypt.NewSIV(bytes.Repeat([]byte{7}, 64))
+	for _, p := range []string{"", "a", "exactly16bytes!!", "alice@example.com is longer than a block"} {
+		c1, c2 := s.Seal([]byte(p), []byte("users.email")), s.Seal([]byte(p), []byte("users.email"))
+		if !bytes.Equal(c1, c2) {
+			t.Errorf("got different ciphertexts for %q", p)
+		}
+		if c3 := s.Seal([]byte(p), []byte("admins.email")); bytes.Equal(c1, c3) {
+			t.Errorf("got same ciphertext for different ad %q", p)
+		}
+		if got, err := s.Open(c1, []byte("users.email")); err != nil || string(got) != p {
+			t.Errorf("got result %q %v, want %q", got, err, p)
+		}
+	}
+	if _, err := encrypt.NewSIV(make([]byte, 16)); !errors.Is(err, encrypt.ErrInvalidSIVKey) {
+		t.Errorf("got err %v, want %v", err, encrypt.ErrInvalidSIVKey)
+	}
+}